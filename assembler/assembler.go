@@ -0,0 +1,281 @@
+// Package assembler ties the parser and code packages together and translates
+// a Hack assembler program into machine words.
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const initCodeSize = 1000
+
+// Options of Assemble
+type Options struct {
+	// Name of the source file. It is used in locations of errors
+	Name string
+	// MaxErrors is the max number of errors collected before assembling stops.
+	// Zero means there is no limit
+	MaxErrors int
+	// Symbols is a symbol table the program is assembled with.
+	// If it is nil, a new table with predefined symbols is created
+	Symbols *code.SymbolTable
+	// IncludeDirs are searched for included files that are not found next to the including file
+	IncludeDirs []string
+	// Defines are constants defined before assembling, i.e. by "-D NAME=value".
+	// They can be used in conditions of ".if" like constants of ".equ"
+	Defines map[string]int
+	// DataImage means data directives do not produce instructions that write the data
+	// at the program start. RAM must be preloaded with Program.DataImage instead
+	DataImage bool
+	// Pseudo enables pseudo-instructions like "mov X, Y" or "push D" that are expanded
+	// into real instructions
+	Pseudo bool
+	// Relocatable means the program is assembled as a module of an object file:
+	// references to labels are relocated and variables are allocated by the linker
+	Relocatable bool
+}
+
+// SourceFile contains all lines of a source file
+type SourceFile struct {
+	Name  string
+	Lines []string
+}
+
+// Program is a result of assembling
+type Program struct {
+	// Words are encoded instructions (as specified), one word per ROM address
+	Words []code.Word
+	// Instructions are source lines of Words, i.e. Instructions[i] is encoded into Words[i]
+	Instructions []Line
+	// Sources are all source files of the program
+	Sources []SourceFile
+	// Symbols contains predefined symbols, labels and variables of the program
+	Symbols *code.SymbolTable
+	// Data are blocks of RAM defined by data directives
+	Data []DataBlock
+	// Diagnostics are all errors arisen while assembling
+	Diagnostics ErrorList
+
+	mod *module
+}
+
+// Assemble reads Hack assembler code from r and returns the assembled Program.
+// Assembling goes on after a wrong line, so all errors are collected in Diagnostics.
+// If there is any error, the Program is returned along with Diagnostics as ErrorList
+func Assemble(r io.Reader, opts Options) (*Program, error) {
+	return assemble([]*codeReader{newCodeReader(bufio.NewReader(r), opts.Name)}, opts)
+}
+
+// AssembleFiles assembles files as one program: they are concatenated in the given order
+// and share the symbol table, so a label of one file can be used in another one.
+// Options.Name is not used as every file has its own name. An error of reading
+// a file is returned before assembling
+func AssembleFiles(names []string, opts Options) (*Program, error) {
+	inputs := make([]*codeReader, 0, len(names))
+	for _, name := range names {
+		content, err := ioutil.ReadFile(name)
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, newCodeReader(bufio.NewReader(bytes.NewReader(content)), name))
+	}
+	return assemble(inputs, opts)
+}
+
+func assemble(inputs []*codeReader, opts Options) (*Program, error) {
+	st := opts.Symbols
+	if st == nil {
+		st = code.NewSymbolTable()
+	}
+	prog := &Program{Symbols: st}
+	diag := &diagnostics{max: opts.MaxErrors}
+
+	// Defines are added in order of names, so their errors are always the same
+	defines := make([]string, 0, len(opts.Defines))
+	for name := range opts.Defines {
+		defines = append(defines, name)
+	}
+	sort.Strings(defines)
+	for _, name := range defines {
+		if _, err := st.AddConst(name, opts.Defines[name]); err != nil {
+			diag.add(err)
+		}
+	}
+
+	pre := newPreprocessor(inputs, opts.IncludeDirs, st, diag)
+	pre.pseudo = opts.Pseudo
+	codeLines, mod := readAsmCode(pre, st, diag)
+	prog.Sources = pre.sources()
+	mod.constUses = append(mod.constUses, pre.constUses...)
+	mod.relocatable = opts.Relocatable
+	mod.checkExports(st, diag)
+	mod.checkLocals(st, diag)
+	codeLines = placeData(codeLines, mod, prog, opts, diag)
+	if !diag.full() {
+		prog.Words, prog.Instructions = encodeAsm(codeLines, mod, st, diag)
+	}
+	prog.mod = mod
+
+	prog.Diagnostics = diag.errs
+	return prog, diag.err()
+}
+
+// placeData allocates RAM for data of the module. Unless opts.DataImage is set, the data
+// is written by instructions at the program start, so they are added before code lines.
+// A relocatable module cannot allocate RAM by directives
+func placeData(codeLines []Line, mod *module, prog *Program, opts Options, diag *diagnostics) []Line {
+	if opts.Relocatable && len(mod.ramLines) > 0 {
+		diag.add(mod.ramLines[0].locate(&code.EncoderError{Msg: "RAM cannot be allocated in a relocatable module"}))
+		return codeLines
+	}
+	if len(mod.data) == 0 {
+		return codeLines
+	}
+
+	if !opts.DataImage {
+		mod.shiftLabels(mod.dataSize()*initInstrSize, prog.Symbols, diag)
+	}
+	prog.Data = mod.allocData(prog.Symbols, diag)
+	if opts.DataImage {
+		return codeLines
+	}
+	return append(initLines(prog.Data), codeLines...)
+}
+
+// readAsmCode reads code lines, adds all labels and constants to Symbol table and retruns all asm lines
+// without spaces and comments. Wrong labels are skipped and their errors are added to diag
+func readAsmCode(asmReader lineReader, st *code.SymbolTable, diag *diagnostics) ([]Line, *module) {
+	asmLines := make([]Line, 0, initCodeSize)
+	mod := newModule()
+	labelParser := parser.NewLabelParser()
+	dirParser := parser.NewDirectiveParser()
+
+	romCount := 0
+	// scope is the last global label of the file. Local labels are added to the symbol
+	// table with full names in the scope. Every file has its own scope
+	scope := ""
+	scopes := make(map[fileKey]string)
+	file := fileKey{}
+	for !diag.full() {
+		line, err := asmReader.readNextCodeLine()
+		if err != nil {
+			break
+		}
+		if key := line.file(); key != file {
+			scopes[file] = scope
+			file, scope = key, scopes[key]
+		}
+
+		if parser.IsLabelLine(line.Text) {
+			label, err := labelParser.Parse(line.Text)
+			if err != nil {
+				diag.add(line.locate(err))
+				continue
+			}
+			name := label.Value
+			if label.IsLocal() {
+				name, err = scopedName(scope, name)
+			} else if line.Loc.Macro == "" {
+				// Labels of macros are unique in every expansion, so they do not open a scope
+				scope = name
+			}
+			if err == nil {
+				_, err = st.AddLabel(name, romCount)
+			}
+			if err != nil {
+				diag.add(line.locate(err))
+			}
+		} else if parser.IsDirectiveLine(line.Text) {
+			d, err := dirParser.Parse(line.Text)
+			if err == nil {
+				d.Args, err = localNames(d.Args, scope, line, mod)
+			}
+			if err == nil {
+				err = readDirective(d, line, romCount, st, mod)
+			}
+			if err != nil {
+				diag.add(line.locate(err))
+			}
+		} else {
+			// Instructions are encoded with full names of local labels
+			line.Text, err = localNames(line.Text, scope, line, mod)
+			if err != nil {
+				diag.add(line.locate(err))
+				continue
+			}
+			asmLines = append(asmLines, line)
+			romCount++
+		}
+	}
+	return asmLines, mod
+}
+
+// readDirective executes a directive of the label pass
+func readDirective(d *parser.Directive, line Line, romCount int, st *code.SymbolTable, mod *module) error {
+	switch d.Name {
+	case equDirective:
+		return mod.defineConst(d.Args, line, romCount, st)
+	case exportDirective:
+		return mod.export(d.Args, line)
+	case importDirective:
+		return mod.importSymbols(d.Args, line)
+	case varDirective, arrayDirective:
+		return mod.reserveVar(d, line, st)
+	case dataDirective:
+		return mod.defineData(d.Args, line)
+	case wordDirective:
+		return mod.addWords(d.Args, line)
+	case stringDirective:
+		return mod.addString(d.Args, line)
+	}
+	return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Unknown directive '.%s'", d.Name)}
+}
+
+// encodeAsm returns encoded asm lines and the lines themselves. Errors of wrong lines
+// are added to diag, so the result is not complete if there is any error
+func encodeAsm(asmCode []Line, mod *module, st *code.SymbolTable, diag *diagnostics) ([]code.Word, []Line) {
+	encoded := make([]code.Word, 0, len(asmCode))
+	encodedLines := make([]Line, 0, len(asmCode))
+	aParser := parser.NewAParser()
+	cParser := parser.NewCParser()
+
+	encodeLine := func(line Line, addr int) (code.Word, error) {
+		if parser.IsAInstrLine(line.Text) {
+			ai, err := aParser.Parse(line.Text)
+			if err != nil {
+				return 0, err
+			}
+			if err := mod.consts.checkUse(*ai, addr); err != nil {
+				return 0, err
+			}
+			return mod.encodeAInstr(*ai, addr, st)
+		}
+
+		ci, err := cParser.Parse(line.Text)
+		if err != nil {
+			return 0, err
+		}
+		return code.EncodeCInstr(*ci)
+	}
+
+	for addr, v := range asmCode {
+		if diag.full() {
+			break
+		}
+		enc, err := encodeLine(v, addr)
+		if err != nil {
+			diag.add(v.locate(err))
+			continue
+		}
+		encoded = append(encoded, enc)
+		encodedLines = append(encodedLines, v)
+	}
+	return encoded, encodedLines
+}
@@ -0,0 +1,586 @@
+package assembler
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func TestReadAsmCodeLabels(t *testing.T) {
+	asm := `
+		(L0)
+		@1 		// 0
+		(L1)
+		D=-1 	// 1
+		M=D		// 2
+		(L2)
+
+		// Comment
+		@1		// 3
+	`
+	want := map[string]int{"L0": 0, "L1": 1, "L2": 3}
+
+	symTable := code.NewSymbolTable()
+	reader := bufio.NewReader(strings.NewReader(asm))
+	diag := &diagnostics{}
+	readAsmCode(newCodeReader(reader, ""), symTable, diag)
+	if err := diag.err(); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	for name, wantAddr := range want {
+		actual, err := symTable.Get(name)
+		if err != nil {
+			t.Errorf("Labed %s not in symtable", name)
+			return
+		}
+		if actual != wantAddr {
+			t.Errorf("Address of %s:%v; want %v", name, actual, wantAddr)
+		}
+	}
+}
+
+func TestReadAsmCodeResult(t *testing.T) {
+	asm := `
+		(L0)
+		@1
+		(L1)
+		D=-1
+		M=D
+		(L2)
+
+		// Comment
+		@1
+	`
+
+	want := []string{
+		"@1",
+		"D=-1",
+		"M=D",
+		"@1",
+	}
+
+	symTable := code.NewSymbolTable()
+	reader := bufio.NewReader(strings.NewReader(asm))
+	diag := &diagnostics{}
+	actual, _ := readAsmCode(newCodeReader(reader, ""), symTable, diag)
+	if err := diag.err(); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v", len(actual), len(want))
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine.Text != want[i] {
+			t.Errorf("Line %v, Actual %v; want %v", i, actualLine.Text, want[i])
+			return
+		}
+	}
+}
+
+func TestAssemble(t *testing.T) {
+	asm := `
+		@R0
+		D=M              // D = first number
+		@R1
+		D=D-M            // D = first number - second number
+		@OUTPUT_FIRST
+		D;JGT            // if D>0 (first is greater) goto output_first
+		@R1
+		D=M              // D = second number
+		@OUTPUT_D
+		0;JMP            // goto output_d
+	(OUTPUT_FIRST)
+		@R0             
+		D=M              // D = first number
+	(OUTPUT_D)
+		@R2
+		M=D              // M[2] = D (greatest number)
+	(INFINITE_LOOP)
+		@INFINITE_LOOP
+		0;JMP
+	`
+	want := [...]string{
+		"0000000000000000",
+		"1111110000010000",
+		"0000000000000001",
+		"1111010011010000",
+		"0000000000001010",
+		"1110001100000001",
+		"0000000000000001",
+		"1111110000010000",
+		"0000000000001100",
+		"1110101010000111",
+		"0000000000000000",
+		"1111110000010000",
+		"0000000000000010",
+		"1110001100001000",
+		"0000000000001110",
+		"1110101010000111",
+	}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	actual := prog.Words
+
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v", len(actual), len(want))
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine.String() != want[i] {
+			t.Errorf("Line %v, Actual %v; want %v", i, actualLine, want[i])
+			return
+		}
+	}
+}
+
+func TestAssembleLastLineWithoutBreak(t *testing.T) {
+	prog, err := Assemble(strings.NewReader("@1\nD=A"), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(prog.Words) != 2 {
+		t.Errorf("Actual len: %v; want len: %v", len(prog.Words), 2)
+	}
+}
+
+func TestAssembleDuplicateLabel(t *testing.T) {
+	asm := `
+		(L0)
+		@L0
+		(L0)
+		0;JMP
+	`
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err == nil {
+		t.Errorf("Error was not arisen as expected. Actual: %v", prog.Words)
+		return
+	}
+	if len(prog.Diagnostics) != 1 {
+		t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+	}
+}
+
+func TestAssembleErrorLocation(t *testing.T) {
+	testCases := []struct {
+		asm  string
+		want parser.Location
+	}{
+		{
+			asm:  "@1\n\t(1LABEL)\n",
+			want: parser.Location{File: "prog.asm", Line: 2, Col: 3, Source: "\t(1LABEL)"},
+		},
+		{
+			asm:  "@1\n// Comment\n  D=X\n",
+			want: parser.Location{File: "prog.asm", Line: 3, Col: 3, Source: "  D=X"},
+		},
+		{
+			asm:  "(L0)\n@L0\n(L0)",
+			want: parser.Location{File: "prog.asm", Line: 3, Col: 1, Source: "(L0)"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{Name: "prog.asm"})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+				return
+			}
+			err := prog.Diagnostics[0]
+			var actual parser.Location
+			var pe *parser.ParseError
+			var ee *code.EncoderError
+			switch {
+			case errors.As(err, &pe):
+				actual = pe.Loc
+			case errors.As(err, &ee):
+				actual = ee.Loc
+			default:
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if actual != tc.want {
+				t.Errorf("Actual: %+v, want: %+v", actual, tc.want)
+			}
+		})
+	}
+}
+
+func TestAssembleAllErrors(t *testing.T) {
+	asm := `
+		(1L)
+		@1
+		D=X
+		@2@
+		(L2)
+		(L2)
+		M=D;JJJ
+	`
+	// Label errors come first as labels are read before encoding
+	wantLines := []int{2, 7, 4, 5, 8}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	var list ErrorList
+	if !errors.As(err, &list) {
+		t.Errorf("Error is not ErrorList: %v", err)
+		return
+	}
+	if len(prog.Diagnostics) != len(wantLines) {
+		t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), len(wantLines))
+		return
+	}
+	for i, e := range prog.Diagnostics {
+		var actual int
+		var pe *parser.ParseError
+		var ee *code.EncoderError
+		switch {
+		case errors.As(e, &pe):
+			actual = pe.Loc.Line
+		case errors.As(e, &ee):
+			actual = ee.Loc.Line
+		}
+		if actual != wantLines[i] {
+			t.Errorf("Error %v: actual line %v; want %v", e, actual, wantLines[i])
+		}
+	}
+}
+
+func TestAssembleMaxErrors(t *testing.T) {
+	asm := "D=X\nD=X\nD=X\nD=X\n"
+
+	prog, _ := Assemble(strings.NewReader(asm), Options{MaxErrors: 2})
+	if len(prog.Diagnostics) != 3 {
+		t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 3)
+		return
+	}
+	if !errors.Is(prog.Diagnostics[2], ErrTooManyErrors) {
+		t.Errorf("The last error: %v; want %v", prog.Diagnostics[2], ErrTooManyErrors)
+	}
+}
+
+func TestAssembleExpressions(t *testing.T) {
+	asm := `
+		@END-1       // forward reference
+		D=A
+		@SCREEN+32
+		M=D
+		@(END+1)*2
+	(END)
+		0;JMP
+	`
+	want := []int{4, 16416, 12}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	for i, addr := range []int{0, 2, 4} {
+		if actual := prog.Words[addr].Value(); actual != want[i] {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, want[i])
+		}
+	}
+}
+
+func TestAssembleExpressionErrors(t *testing.T) {
+	asm := "@SCREEN*2\n@-1\n@1/0\n@UNKNOWN+1\n"
+
+	prog, _ := Assemble(strings.NewReader(asm), Options{})
+	if len(prog.Diagnostics) != 4 {
+		t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 4)
+		return
+	}
+	for _, e := range prog.Diagnostics {
+		var ee *code.EncoderError
+		if !errors.As(e, &ee) {
+			t.Errorf("Error is not EncoderError: %v", e)
+		}
+	}
+}
+
+func TestAssembleConstants(t *testing.T) {
+	asm := `
+		.equ ROWWORDS 32        // words in a screen row
+		.equ LAST SCREEN+ROWWORDS*255
+		@ROWWORDS
+		D=A
+		@LAST
+		M=D
+		@i
+		M=0
+	`
+	want := []int{32, code.ScreenAddr + 32*255, 16}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	for i, addr := range []int{0, 2, 4} {
+		if actual := prog.Words[addr].Value(); actual != want[i] {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, want[i])
+		}
+	}
+	if kind := prog.Symbols.Kinds["ROWWORDS"]; kind != code.ConstSymbol {
+		t.Errorf("Kind of ROWWORDS: %v; want %v", kind, code.ConstSymbol)
+	}
+}
+
+func TestAssembleConstantErrors(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		wantLine int
+	}{
+		{asm: ".equ N 1\n.equ N 2\n", wantLine: 2},
+		{asm: "(LOOP)\n.equ LOOP 1\n", wantLine: 2},
+		{asm: ".equ N M+1\n.equ M 1\n", wantLine: 1},
+		{asm: "@N\nD=A\n.equ N 1\n", wantLine: 1},
+		{asm: "@N+1\n.equ N 1\n", wantLine: 1},
+		{asm: ".equ 1N 1\n", wantLine: 1},
+		{asm: ".equ N\n", wantLine: 1},
+		{asm: ".equ N 0x8000\n", wantLine: 1},
+		{asm: ".unknown N\n", wantLine: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+				return
+			}
+			var actual int
+			var pe *parser.ParseError
+			var ee *code.EncoderError
+			switch err := prog.Diagnostics[0]; {
+			case errors.As(err, &pe):
+				actual = pe.Loc.Line
+			case errors.As(err, &ee):
+				actual = ee.Loc.Line
+			}
+			if actual != tc.wantLine {
+				t.Errorf("Error %v: actual line %v; want %v", prog.Diagnostics[0], actual, tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestAssembleLocalLabels(t *testing.T) {
+	asm := `
+	.macro WAIT
+	(SPIN)
+		@SPIN
+		0;JMP
+	.endm
+	(FILL)
+	(.loop)
+		@.loop
+		0;JMP
+		WAIT
+	(.end)
+		@.end-1
+	(CLEAR)
+	(.loop)
+		@.loop
+		@FILL.end
+	`
+	want := map[int]int{0: 0, 2: 2, 4: 3, 5: 5, 6: 4}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	for addr, v := range want {
+		if actual := prog.Words[addr].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, v)
+		}
+	}
+	for _, name := range []string{"FILL.loop", "FILL.end", "CLEAR.loop"} {
+		if kind := prog.Symbols.Kinds[name]; !prog.Symbols.Exists(name) || kind != code.LabelSymbol {
+			t.Errorf("Label %v is not in the symbol table", name)
+		}
+	}
+}
+
+func TestAssembleLocalLabelErrors(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		wantLine int
+	}{
+		{asm: "(.loop)\n", wantLine: 1},
+		{asm: "@.loop\n", wantLine: 1},
+		{asm: "(MAIN)\n(.loop)\n(.loop)\n", wantLine: 3},
+		{asm: "(MAIN)\n(.loop)\n(MAIN.loop)\n", wantLine: 3},
+		{asm: "(MAIN)\n@.loop\n(OTHER)\n(.loop)\n", wantLine: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+				return
+			}
+			var actual int
+			var pe *parser.ParseError
+			var ee *code.EncoderError
+			switch err := prog.Diagnostics[0]; {
+			case errors.As(err, &pe):
+				actual = pe.Loc.Line
+			case errors.As(err, &ee):
+				actual = ee.Loc.Line
+			}
+			if actual != tc.wantLine {
+				t.Errorf("Error %v: actual line %v; want %v", prog.Diagnostics[0], actual, tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestAssembleRAMReservation(t *testing.T) {
+	asm := `
+		.equ BASE 100
+		.var flag @BASE*2
+		.array buf 3
+		.array row 32 @BASE
+		@i
+		@flag
+		@buf+2
+		@row
+	`
+	want := []int{19, 200, 18, 100}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	for addr, v := range want {
+		if actual := prog.Words[addr].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, v)
+		}
+	}
+}
+
+func TestAssembleRAMReservationErrors(t *testing.T) {
+	testCases := []struct {
+		asm  string
+		opts Options
+	}{
+		{asm: ".var x @5\n"},
+		{asm: ".var x @SCREEN\n"},
+		{asm: ".var x @100\n.var y @100\n"},
+		{asm: ".array a 10 @100\n.var y @105\n"},
+		{asm: ".array a 0\n"},
+		{asm: ".array a\n"},
+		{asm: ".array a N\n"},
+		{asm: ".var x 100\n"},
+		{asm: ".var 1x @100\n"},
+		{asm: "(x)\n.var x @100\n"},
+		{asm: ".var x @100\n", opts: Options{Relocatable: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), tc.opts)
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+			}
+		})
+	}
+}
+
+func TestAssemblePseudo(t *testing.T) {
+	asm := `
+		.macro MOV2 DST SRC
+		mov DST, SRC
+		.endm
+		mov x, #5
+		MOV2 y, x
+		jz D END
+		goto 0
+		(END)
+	`
+	want := "@5\nD=A\n@x\nM=D\n@x\nD=M\n@y\nM=D\n@END\nD;JEQ\n@0\n0;JMP\n(END)\n"
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Pseudo: true})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	wantProg, err := Assemble(strings.NewReader(want), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(prog.Words) != len(wantProg.Words) {
+		t.Errorf("Actual len: %v; want len: %v", len(prog.Words), len(wantProg.Words))
+		return
+	}
+	for i, w := range wantProg.Words {
+		if prog.Words[i] != w {
+			t.Errorf("Word %v: actual %v; want %v", i, prog.Words[i], w)
+		}
+	}
+}
+
+func TestAssemblePseudoLocalLabels(t *testing.T) {
+	asm := "(MAIN)\n(.loop)\njz D .end\ngoto .loop\n(.end)\n"
+	want := "(MAIN)\n(.loop)\n@.end\nD;JEQ\n@.loop\n0;JMP\n(.end)\n"
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Pseudo: true})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	wantProg, err := Assemble(strings.NewReader(want), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(prog.Words) != len(wantProg.Words) {
+		t.Errorf("Actual len: %v; want len: %v", len(prog.Words), len(wantProg.Words))
+		return
+	}
+	for i, w := range wantProg.Words {
+		if prog.Words[i] != w {
+			t.Errorf("Word %v: actual %v; want %v", i, prog.Words[i], w)
+		}
+	}
+}
+
+func TestAssemblePseudoErrors(t *testing.T) {
+	testCases := []struct {
+		asm  string
+		opts Options
+	}{
+		{asm: "mov x, #5\n"},
+		{asm: "mov x\n", opts: Options{Pseudo: true}},
+		{asm: "push A\n", opts: Options{Pseudo: true}},
+		{asm: "@1\ngoto 1L\n", opts: Options{Pseudo: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), tc.opts)
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+			}
+		})
+	}
+}
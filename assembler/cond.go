@@ -0,0 +1,187 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// Directives of conditional assembly
+const (
+	ifDirective     = "if"
+	ifdefDirective  = "ifdef"
+	ifndefDirective = "ifndef"
+	elseDirective   = "else"
+	endifDirective  = "endif"
+)
+
+// Comparison operators of ".if". Two-rune operators go first
+var condOps = []string{"==", "!=", "<=", ">=", "<", ">"}
+
+// cond is an open ".if" block
+type cond struct {
+	line Line
+	// active means lines of the current branch are assembled
+	active bool
+	// taken means one of the branches is already chosen
+	taken    bool
+	elseSeen bool
+}
+
+// active returns true if lines are assembled, i.e. all open ".if" blocks are in active branches
+func (p *preprocessor) active() bool {
+	return len(p.conds) == 0 || p.conds[len(p.conds)-1].active
+}
+
+// condDirective executes a directive of conditional assembly. It returns false
+// if d is another directive
+func (p *preprocessor) condDirective(line Line, d *parser.Directive) (bool, error) {
+	switch d.Name {
+	case ifDirective, ifdefDirective, ifndefDirective:
+		c := cond{line: line, taken: true}
+		if !p.active() {
+			p.conds = append(p.conds, c)
+			return true, nil
+		}
+		ok, err := p.evalCond(line, d)
+		// A wrong condition leaves all branches of the block inactive
+		c.active, c.taken = ok && err == nil, ok || err != nil
+		p.conds = append(p.conds, c)
+		return true, err
+	case elseDirective:
+		if len(p.conds) == 0 {
+			return true, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", elseDirective, ifDirective)}
+		}
+		c := &p.conds[len(p.conds)-1]
+		if c.elseSeen {
+			return true, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' is repeated", elseDirective)}
+		}
+		c.elseSeen = true
+		c.active = !c.taken
+		c.taken = true
+		return true, nil
+	case endifDirective:
+		if len(p.conds) == 0 {
+			return true, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", endifDirective, ifDirective)}
+		}
+		p.conds = p.conds[:len(p.conds)-1]
+		return true, nil
+	}
+	return false, nil
+}
+
+// closeConds adds errors of ".if" blocks that are not closed at the end of a file.
+// Blocks opened before the first n are left open
+func (p *preprocessor) closeConds(n int) {
+	if n > len(p.conds) {
+		return
+	}
+	for _, c := range p.conds[n:] {
+		p.diag.add(c.line.locate(&parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' has no '.%s'", ifDirective, endifDirective)}))
+	}
+	p.conds = p.conds[:n]
+}
+
+// evalCond returns the value of the condition. ".ifdef NAME" is true if the constant is defined,
+// ".if EXPR" is true if the expression is not zero. The expression can be a comparison
+// of two expressions like ".if WIDTH == 512". Only constants can be used in conditions
+func (p *preprocessor) evalCond(line Line, d *parser.Directive) (bool, error) {
+	if d.Name != ifDirective {
+		if !parser.IsSymbolName(d.Args) {
+			return false, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong constant name '%s'", d.Args)}
+		}
+		defined := p.symbols.Exists(d.Args) && p.symbols.Kinds[d.Args] == code.ConstSymbol
+		return defined == (d.Name == ifdefDirective), nil
+	}
+
+	if d.Args == "" {
+		return false, &parser.ParseError{Pos: 1, Msg: "Condition is expected"}
+	}
+	left, op, right := splitCondition(d.Args)
+	p.constUses = append(p.constUses, constRefs(left, line)...)
+	p.constUses = append(p.constUses, constRefs(right, line)...)
+	x, err := evalConst(left, p.symbols)
+	if err != nil || op == "" {
+		return x != 0, err
+	}
+	y, err := evalConst(right, p.symbols)
+	if err != nil {
+		return false, err
+	}
+
+	switch op {
+	case "==":
+		return x == y, nil
+	case "!=":
+		return x != y, nil
+	case "<=":
+		return x <= y, nil
+	case ">=":
+		return x >= y, nil
+	case "<":
+		return x < y, nil
+	}
+	return x > y, nil
+}
+
+// evalConst returns the value of an expression over constants and predefined symbols
+func evalConst(s string, st *code.SymbolTable) (int, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return 0, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong expression '%s': %v", strings.TrimSpace(s), err)}
+	}
+	n, err := expr.Eval(func(name string) (int, error) {
+		if !st.Exists(name) {
+			return 0, fmt.Errorf("Constant '%s' is not defined", name)
+		}
+		if kind := st.Kinds[name]; kind != code.ConstSymbol && kind != code.PredefinedSymbol {
+			return 0, fmt.Errorf("'%s' is a %v, but only constants can be used here", name, kind)
+		}
+		return st.Get(name)
+	})
+	if err != nil {
+		return 0, &code.EncoderError{Msg: fmt.Sprintf("Cannot evaluate '%s': %v", strings.TrimSpace(s), err)}
+	}
+	return n, nil
+}
+
+// constRefs returns symbols of the expression s used in the line
+func constRefs(s string, line Line) []symbolRef {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return nil
+	}
+	var refs []symbolRef
+	for _, name := range parser.ExprSymbols(expr) {
+		refs = append(refs, symbolRef{name: name, line: line})
+	}
+	return refs
+}
+
+// splitCondition splits s by the first comparison operator. Shifts "<<" and ">>"
+// and characters in literals are not comparisons. If there is no comparison, op is empty
+func splitCondition(s string) (left, op, right string) {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\'':
+			// Skip a character literal
+			for i++; i < len(s) && s[i] != '\''; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+			continue
+		case strings.HasPrefix(s[i:], "<<") || strings.HasPrefix(s[i:], ">>"):
+			i++
+			continue
+		}
+		for _, op := range condOps {
+			if strings.HasPrefix(s[i:], op) {
+				return s[:i], op, s[i+len(op):]
+			}
+		}
+	}
+	return s, "", ""
+}
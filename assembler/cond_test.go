@@ -0,0 +1,129 @@
+package assembler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func TestAssembleConditions(t *testing.T) {
+	testCases := []struct {
+		name    string
+		asm     string
+		defines map[string]int
+		want    []int
+	}{
+		{
+			name: "if",
+			asm:  ".equ DEBUG 1\n.if DEBUG\n@1\n.else\n@2\n.endif\n@3\n",
+			want: []int{1, 3},
+		},
+		{
+			name: "else",
+			asm:  ".equ DEBUG 0\n.if DEBUG\n@1\n.else\n@2\n.endif\n@3\n",
+			want: []int{2, 3},
+		},
+		{
+			name: "comparison",
+			asm:  ".equ WIDTH 512\n.if WIDTH/2 == 256\n@1\n.endif\n.if WIDTH < 1<<8\n@2\n.endif\n",
+			want: []int{1},
+		},
+		{
+			name:    "define",
+			asm:     ".if WIDTH != 512\n@WIDTH\n.endif\n",
+			defines: map[string]int{"WIDTH": 256},
+			want:    []int{256},
+		},
+		{
+			name: "ifdef",
+			asm:  ".ifdef TRACE\n@1\n.endif\n.ifndef TRACE\n@2\n.endif\n",
+			want: []int{2},
+		},
+		{
+			name:    "ifndef default",
+			asm:     ".ifndef TRACE\n.equ TRACE 0\n.endif\n@TRACE\n",
+			defines: map[string]int{"TRACE": 1},
+			want:    []int{1},
+		},
+		{
+			name: "nested",
+			asm:  ".equ A 0\n.if A\n.if 1\n@1\n.else\n@2\n.endif\n(X)\n.unknown\n.else\n@3\n.endif\n",
+			want: []int{3},
+		},
+		{
+			name: "macro",
+			asm:  ".macro M N\n.if N > 1\n@N\n.endif\n.endm\nM 1\nM 2\n",
+			want: []int{2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prog, err := Assemble(strings.NewReader(tc.asm), Options{Defines: tc.defines})
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			actual := make([]int, 0, len(prog.Words))
+			for _, w := range prog.Words {
+				actual = append(actual, w.Value())
+			}
+			if !reflect.DeepEqual(actual, tc.want) {
+				t.Errorf("Actual: %v; want %v", actual, tc.want)
+			}
+		})
+	}
+}
+
+func TestAssembleConditionErrors(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		wantLine int
+	}{
+		{asm: "@1\n.if 1\n@2\n", wantLine: 2},
+		{asm: ".else\n", wantLine: 1},
+		{asm: ".endif\n", wantLine: 1},
+		{asm: ".if 1\n.else\n.else\n.endif\n", wantLine: 3},
+		{asm: ".if UNKNOWN\n.endif\n", wantLine: 1},
+		{asm: ".if UNKNOWN\n@1\n.else\n@2\n.unknown\n.endif\n", wantLine: 1},
+		{asm: ".if 1 == UNKNOWN\n.else\n.unknown\n.endif\n", wantLine: 1},
+		{asm: "(L)\n.if L\n.endif\n", wantLine: 2},
+		{asm: ".if\n.endif\n", wantLine: 1},
+		{asm: ".if 1 == \n.endif\n", wantLine: 1},
+		{asm: ".ifdef 1A\n.endif\n", wantLine: 1},
+		{asm: ".if 1\n.unknown\n.endif\n", wantLine: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+				return
+			}
+			var actual int
+			var pe *parser.ParseError
+			var ee *code.EncoderError
+			switch err := prog.Diagnostics[0]; {
+			case errors.As(err, &pe):
+				actual = pe.Loc.Line
+			case errors.As(err, &ee):
+				actual = ee.Loc.Line
+			}
+			if actual != tc.wantLine {
+				t.Errorf("Error %v: actual line %v; want %v", prog.Diagnostics[0], actual, tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestAssembleDefineError(t *testing.T) {
+	_, err := Assemble(strings.NewReader(".equ N 2\n"), Options{Defines: map[string]int{"N": 1}})
+	if err == nil {
+		t.Errorf("No error for redefined constant")
+	}
+}
@@ -0,0 +1,252 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// Directives of data in RAM
+const (
+	dataDirective   = "data"
+	wordDirective   = "word"
+	stringDirective = "string"
+)
+
+const (
+	minDataValue = -1 << 15
+	maxDataValue = 1<<16 - 1
+	// initInstrSize is the number of instructions that write a word of data:
+	// @value, D=A or D=!A, @addr, M=D
+	initInstrSize = 4
+)
+
+// DataBlock is a part of RAM initialized by ".data NAME" and following ".word" and ".string"
+type DataBlock struct {
+	Name  string
+	Addr  int
+	Words []code.Word
+	// Locs are locations of directives of the words
+	Locs []parser.Location
+}
+
+// dataDef is a block of data read by the label pass. Values are constant expressions
+type dataDef struct {
+	name   string
+	line   Line
+	values []symbolRef
+}
+
+// defineData starts a block of data ".data NAME"
+func (m *module) defineData(args string, line Line) error {
+	if !parser.IsSymbolName(args) {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong data name '%s'", args)}
+	}
+	m.data = append(m.data, dataDef{name: args, line: line})
+	m.ramLines = append(m.ramLines, line)
+	return nil
+}
+
+// addWords adds values of ".word VALUE1, VALUE2" to the last block of data
+func (m *module) addWords(args string, line Line) error {
+	if len(m.data) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", wordDirective, dataDirective)}
+	}
+	values := splitList(args)
+	if len(values) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: "Value is expected"}
+	}
+	def := &m.data[len(m.data)-1]
+	for _, v := range values {
+		if v == "" {
+			return &parser.ParseError{Pos: 1, Msg: "Value is expected"}
+		}
+		def.values = append(def.values, symbolRef{name: v, line: line})
+	}
+	return nil
+}
+
+// addString adds characters of the string literal of ".string" and the terminating zero
+// to the last block of data
+func (m *module) addString(args string, line Line) error {
+	if len(m.data) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", stringDirective, dataDirective)}
+	}
+	s, err := parser.ParseString(args)
+	if err != nil {
+		return &parser.ParseError{Pos: 1, Msg: err.Error()}
+	}
+	def := &m.data[len(m.data)-1]
+	for _, r := range s + "\x00" {
+		if r > maxAValue {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Character '%c' cannot be a word of data", r)}
+		}
+		def.values = append(def.values, symbolRef{name: strconv.Itoa(int(r)), line: line})
+	}
+	return nil
+}
+
+// dataSize returns the number of words of all data
+func (m *module) dataSize() int {
+	size := 0
+	for _, def := range m.data {
+		size += len(def.values)
+	}
+	return size
+}
+
+// allocData allocates RAM for all data and evaluates its values. Values can use
+// any symbol defined by the label pass, i.e. addresses of labels
+func (m *module) allocData(st *code.SymbolTable, diag *diagnostics) []DataBlock {
+	blocks := make([]DataBlock, 0, len(m.data))
+	for _, def := range m.data {
+		addr, err := st.AddData(def.name, len(def.values))
+		if err != nil {
+			diag.add(def.line.locate(err))
+			continue
+		}
+
+		block := DataBlock{
+			Name:  def.name,
+			Addr:  addr,
+			Words: make([]code.Word, 0, len(def.values)),
+			Locs:  make([]parser.Location, 0, len(def.values)),
+		}
+		for _, v := range def.values {
+			w, err := evalData(v.name, st)
+			if err != nil {
+				diag.add(v.line.locate(err))
+			}
+			block.Words = append(block.Words, w)
+			block.Locs = append(block.Locs, v.line.Loc)
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks
+}
+
+// evalData returns the word of a value. A negative value is stored as a 16-bit
+// two's complement number
+func evalData(s string, st *code.SymbolTable) (code.Word, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return 0, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong value '%s': %v", s, err)}
+	}
+	n, err := expr.Eval(st.Get)
+	if err != nil {
+		return 0, &code.EncoderError{Msg: fmt.Sprintf("Cannot evaluate %v: %v", s, err)}
+	}
+	if n < minDataValue || n > maxDataValue {
+		return 0, &code.EncoderError{Msg: fmt.Sprintf("Value %v of %v is out of bound %v..%v", n, s, minDataValue, maxDataValue)}
+	}
+	return code.Word(uint16(n)), nil
+}
+
+// shiftLabels moves all labels by n words of initialization code at the program start.
+// Constants computed from labels are evaluated again. They cannot be used by directives
+// evaluated before moving, i.e. by ".if" or ".rept", so it is an error
+func (m *module) shiftLabels(n int, st *code.SymbolTable, diag *diagnostics) {
+	if n == 0 {
+		return
+	}
+	for name, kind := range st.Kinds {
+		if kind == code.LabelSymbol {
+			st.Table[name] += n
+		}
+	}
+	for name := range m.consts {
+		m.consts[name] += n
+	}
+
+	// Constants are evaluated in order of definitions, so a constant can use previous ones
+	for _, c := range m.labelConsts {
+		v, err := m.labelExprs[c.name].Eval(st.Get)
+		if err == nil && (v < 0 || v > maxAValue) {
+			err = fmt.Errorf("value %v is out of bound", v)
+		}
+		if err != nil {
+			diag.add(c.line.locate(&code.EncoderError{
+				Msg: fmt.Sprintf("Cannot evaluate constant '%s' moved by data initialization code: %v", c.name, err),
+			}))
+			continue
+		}
+		st.Table[c.name] = v
+	}
+	for _, u := range m.constUses {
+		if _, ok := m.labelExprs[u.name]; ok {
+			diag.add(u.line.locate(&code.EncoderError{
+				Msg: fmt.Sprintf("Constant '%s' uses a label moved by data initialization code, it cannot be used here", u.name),
+			}))
+		}
+	}
+}
+
+// initLines returns instructions that write data to RAM. Every word takes initInstrSize instructions
+// located at the directive of the word
+func initLines(blocks []DataBlock) []Line {
+	var lines []Line
+	for _, b := range blocks {
+		for i, w := range b.Words {
+			value, comp := fmt.Sprintf("@%d", w), "D=A"
+			if w > maxAValue {
+				value, comp = fmt.Sprintf("@%d", ^w), "D=!A"
+			}
+			addr := "@" + b.Name
+			if i > 0 {
+				addr = fmt.Sprintf("@%s+%d", b.Name, i)
+			}
+			for _, text := range []string{value, comp, addr, "M=D"} {
+				lines = append(lines, Line{Text: text, Loc: b.Locs[i]})
+			}
+		}
+	}
+	return lines
+}
+
+// DataImage returns RAM from the address 0 up to the last word of data.
+// Words that are not data are zero
+func (p *Program) DataImage() []code.Word {
+	size := 0
+	for _, b := range p.Data {
+		if end := b.Addr + len(b.Words); end > size {
+			size = end
+		}
+	}
+	image := make([]code.Word, size)
+	for _, b := range p.Data {
+		copy(image[b.Addr:], b.Words)
+	}
+	return image
+}
+
+// splitList splits s by commas that are not in character or string literals
+func splitList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	var items []string
+	var quote rune
+	escaped := false
+	start := 0
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == '\\':
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ',':
+			items = append(items, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(items, strings.TrimSpace(s[start:]))
+}
@@ -0,0 +1,155 @@
+package assembler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/cpu"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const dataAsm = `
+	.data TABLE
+	.word 5, -1, END, ','
+	.data MSG
+	.string "Hi"
+	@i
+	M=1
+(END)
+	@END
+	0;JMP
+`
+
+func TestAssembleDataInit(t *testing.T) {
+	prog, err := Assemble(strings.NewReader(dataAsm), Options{})
+	if err != nil {
+		t.Fatalf("Error from function: %v", err)
+	}
+
+	// 7 words of data are written by 4 instructions each
+	initSize := 7 * initInstrSize
+	if addr, _ := prog.Symbols.Get("END"); addr != initSize+2 {
+		t.Errorf("END: actual %v; want %v", addr, initSize+2)
+	}
+	word := parser.Location{Line: 3, Col: 2, Source: "\t.word 5, -1, END, ','"}
+	str := parser.Location{Line: 5, Col: 2, Source: "\t.string \"Hi\""}
+	wantData := []DataBlock{
+		{
+			Name:  "TABLE",
+			Addr:  16,
+			Words: []code.Word{5, 0xFFFF, code.Word(initSize + 2), ','},
+			Locs:  []parser.Location{word, word, word, word},
+		},
+		{Name: "MSG", Addr: 20, Words: []code.Word{'H', 'i', 0}, Locs: []parser.Location{str, str, str}},
+	}
+	if !reflect.DeepEqual(prog.Data, wantData) {
+		t.Errorf("Data: actual %v; want %v", prog.Data, wantData)
+	}
+	if addr, _ := prog.Symbols.Get("i"); addr != 23 {
+		t.Errorf("Variable i: actual %v; want %v", addr, 23)
+	}
+
+	for addr, instr := range prog.Instructions[:initSize] {
+		if want := 3 + addr/(4*initInstrSize)*2; instr.Loc.Line != want {
+			t.Errorf("Line of instruction %v: actual %v; want %v", addr, instr.Loc.Line, want)
+		}
+	}
+
+	c := cpu.New()
+	if err := c.Load(prog.Words); err != nil {
+		t.Fatal(err)
+	}
+	c.Run(initSize)
+	for _, b := range prog.Data {
+		for i, w := range b.Words {
+			if actual := c.RAM[b.Addr+i]; actual != uint16(w) {
+				t.Errorf("RAM[%v]: actual %v; want %v", b.Addr+i, actual, w)
+			}
+		}
+	}
+}
+
+func TestAssembleDataImage(t *testing.T) {
+	prog, err := Assemble(strings.NewReader(dataAsm), Options{DataImage: true})
+	if err != nil {
+		t.Fatalf("Error from function: %v", err)
+	}
+	if len(prog.Words) != 4 {
+		t.Errorf("Words len: %v; want %v", len(prog.Words), 4)
+	}
+
+	image := prog.DataImage()
+	if len(image) != 23 {
+		t.Fatalf("Image len: %v; want %v", len(image), 23)
+	}
+	want := []code.Word{5, 0xFFFF, 2, ',', 'H', 'i', 0}
+	if !reflect.DeepEqual(image[16:], want) {
+		t.Errorf("Image: actual %v; want %v", image[16:], want)
+	}
+}
+
+func TestAssembleDataLabelConstants(t *testing.T) {
+	asm := `
+		.data T
+		.word 1
+	(START)
+		.equ HERE START
+		.equ NEXT HERE+1
+		@HERE
+		@NEXT
+	(END)
+		.equ SIZE END-START
+		@SIZE
+		.data U
+		.word HERE
+	`
+	// 2 words of data are written by 8 instructions, so START is 8
+	want := []int{8, 9, 2}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Fatalf("Error from function: %v", err)
+	}
+	initSize := 2 * initInstrSize
+	for i, v := range want {
+		if actual := prog.Words[initSize+i].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", initSize+i, actual, v)
+		}
+	}
+	if actual := prog.Data[1].Words[0]; actual != 8 {
+		t.Errorf("Data U: actual %v; want %v", actual, 8)
+	}
+}
+
+func TestAssembleDataErrors(t *testing.T) {
+	testCases := []struct {
+		name string
+		asm  string
+		opts Options
+	}{
+		{name: "word without data", asm: ".word 1\n"},
+		{name: "string without data", asm: ".string \"a\"\n"},
+		{name: "wrong name", asm: ".data 1A\n"},
+		{name: "duplicate", asm: ".data A\n.word 1\n.data A\n.word 2\n"},
+		{name: "label name", asm: "(A)\n.data A\n.word 1\n"},
+		{name: "no value", asm: ".data A\n.word 1,,2\n"},
+		{name: "unknown symbol", asm: ".data A\n.word x\n"},
+		{name: "out of bound", asm: ".data A\n.word 65536\n"},
+		{name: "wrong string", asm: ".data A\n.string abc\n"},
+		{name: "label constant in if", asm: "(L)\n.equ N L+1\n.if N\n.endif\n.data A\n.word 1\n"},
+		{name: "label constant in rept", asm: "(L)\n.equ N L+1\n.rept N\n.endr\n.data A\n.word 1\n"},
+		{name: "label constant in array", asm: "(L)\n.equ N L+1\n.array B N\n.data A\n.word 1\n"},
+		{name: "relocatable", asm: ".data A\n.word 1\n", opts: Options{Relocatable: true}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), tc.opts)
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+			}
+		})
+	}
+}
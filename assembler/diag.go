@@ -0,0 +1,52 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrTooManyErrors is the last error of ErrorList if the list was cut by Options.MaxErrors
+var ErrTooManyErrors = errors.New("too many errors")
+
+// ErrorList is a list of errors arisen while assembling a program
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	switch len(l) {
+	case 0:
+		return "no errors"
+	case 1:
+		return l[0].Error()
+	}
+	return fmt.Sprintf("%v (and %d more errors)", l[0], len(l)-1)
+}
+
+// diagnostics collects errors. If max is greater than zero, only max errors are collected
+type diagnostics struct {
+	errs ErrorList
+	max  int
+}
+
+// add appends an error to the list. If the list is full, ErrTooManyErrors is added as the last one
+func (d *diagnostics) add(err error) {
+	if d.full() {
+		return
+	}
+	d.errs = append(d.errs, err)
+	if d.max > 0 && len(d.errs) == d.max {
+		d.errs = append(d.errs, ErrTooManyErrors)
+	}
+}
+
+// full returns true if no more errors can be collected
+func (d *diagnostics) full() bool {
+	return d.max > 0 && len(d.errs) > d.max
+}
+
+// err returns collected errors as ErrorList or nil if there are no errors
+func (d *diagnostics) err() error {
+	if len(d.errs) == 0 {
+		return nil
+	}
+	return d.errs
+}
@@ -0,0 +1,131 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// Names of directives
+const (
+	equDirective    = "equ"
+	exportDirective = "export"
+	importDirective = "import"
+	varDirective    = "var"
+	arrayDirective  = "array"
+)
+
+// constDefs keeps ROM addresses of instructions that follow definitions of constants.
+// An instruction before the address cannot use the constant
+type constDefs map[string]int
+
+// defineConst adds a constant ".equ NAME value" to the symbol table. The value is
+// a constant expression that can use only symbols defined above
+func (m *module) defineConst(args string, line Line, romCount int, st *code.SymbolTable) error {
+	name, value := parser.SplitArg(args)
+	if !parser.IsSymbolName(name) {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong constant name '%s'", name)}
+	}
+	if value == "" {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Constant '%s' has no value", name)}
+	}
+
+	expr, err := parser.ParseExpr(value)
+	if err != nil {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong value of constant '%s': %v", name, err)}
+	}
+	usesLabel := false
+	n, err := expr.Eval(func(s string) (int, error) {
+		if !st.Exists(s) {
+			return 0, fmt.Errorf("'%s' is used before its definition", s)
+		}
+		_, labelConst := m.labelExprs[s]
+		usesLabel = usesLabel || labelConst || st.Kinds[s] == code.LabelSymbol
+		return st.Get(s)
+	})
+	if err != nil {
+		return &code.EncoderError{Msg: fmt.Sprintf("Cannot evaluate constant '%s': %v", name, err)}
+	}
+
+	if _, err := st.AddConst(name, n); err != nil {
+		return err
+	}
+	m.consts[name] = romCount
+	if usesLabel {
+		m.labelConsts = append(m.labelConsts, symbolRef{name: name, line: line})
+		m.labelExprs[name] = expr
+	}
+	return nil
+}
+
+// checkUse returns an error if A-Instruction at ROM address addr uses a constant
+// defined below it
+func (c constDefs) checkUse(ai parser.AInstruction, addr int) error {
+	var names []string
+	switch {
+	case ai.IsVar:
+		names = []string{ai.Value}
+	case ai.IsExpr:
+		expr, err := parser.ParseExpr(ai.Value)
+		if err != nil {
+			return err
+		}
+		names = parser.ExprSymbols(expr)
+	}
+
+	for _, name := range names {
+		if def, ok := c[name]; ok && def > addr {
+			return &code.EncoderError{Msg: fmt.Sprintf("Constant '%s' is used before its definition", name)}
+		}
+	}
+	return nil
+}
+
+// reserveVar adds a variable ".var NAME @ADDRESS" or an array ".array NAME SIZE [@ADDRESS]".
+// An array without the address is allocated like other variables. The address and
+// the size are constant expressions
+func (m *module) reserveVar(d *parser.Directive, line Line, st *code.SymbolTable) error {
+	name, rest := parser.SplitArg(d.Args)
+	if !parser.IsSymbolName(name) {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong variable name '%s'", name)}
+	}
+
+	size, addrArg := 1, rest
+	if d.Name == arrayDirective {
+		sizeArg := rest
+		if i := strings.IndexRune(rest, '@'); i >= 0 {
+			sizeArg, addrArg = rest[:i], rest[i:]
+		} else {
+			addrArg = ""
+		}
+		if strings.TrimSpace(sizeArg) == "" {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Size of array '%s' is expected", name)}
+		}
+		var err error
+		m.constUses = append(m.constUses, constRefs(sizeArg, line)...)
+		if size, err = evalConst(sizeArg, st); err != nil {
+			return err
+		}
+		if size < 1 {
+			return &code.EncoderError{Msg: fmt.Sprintf("Size %d of array '%s' must be positive", size, name)}
+		}
+	}
+
+	m.ramLines = append(m.ramLines, line)
+	if addrArg == "" && d.Name == arrayDirective {
+		_, err := st.AddData(name, size)
+		return err
+	}
+	if !strings.HasPrefix(addrArg, "@") {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Address of '%s' is expected as @ADDRESS", name)}
+	}
+	m.constUses = append(m.constUses, constRefs(addrArg[1:], line)...)
+	addr, err := evalConst(addrArg[1:], st)
+	if err != nil {
+		return err
+	}
+	_, err = st.AddVarAt(name, addr, size)
+	return err
+}
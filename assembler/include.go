@@ -0,0 +1,79 @@
+package assembler
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const includeDirective = "include"
+
+// include opens the file of the directive `.include "path.asm"` and puts it on top of the frames.
+// The path is relative to the including file. If it is not found there, include dirs are searched
+func (p *preprocessor) include(line Line, args string) error {
+	name, err := parser.ParseString(args)
+	if err != nil {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Include path must be a quoted string: %v", err)}
+	}
+
+	path, err := p.resolveInclude(name, line.Loc.File)
+	if err != nil {
+		return &parser.ParseError{Pos: 1, Msg: err.Error()}
+	}
+
+	// The chain of including files is restored from locations
+	var chain []string
+	cycle := false
+	for loc := &line.Loc; loc != nil; loc = loc.Parent {
+		if len(chain) == 0 || chain[0] != loc.File {
+			chain = append([]string{loc.File}, chain...)
+		}
+		cycle = cycle || sameFile(loc.File, path)
+	}
+	if cycle {
+		chain = append(chain, path)
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Include cycle: %s", strings.Join(chain, " -> "))}
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Cannot read include file: %v", err)}
+	}
+	reader := newCodeReader(bufio.NewReader(bytes.NewReader(content)), path)
+	parent := line.Loc
+	p.readers = append(p.readers, reader)
+	p.frames = append(p.frames, frame{reader: reader, parent: &parent, conds: len(p.conds)})
+	return nil
+}
+
+// resolveInclude returns the path of the included file
+func (p *preprocessor) resolveInclude(name, from string) (string, error) {
+	if filepath.IsAbs(name) {
+		return name, nil
+	}
+
+	dirs := append([]string{filepath.Dir(from)}, p.includeDirs...)
+	for _, dir := range dirs {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("Include file '%s' is not found in %s", name, strings.Join(dirs, ", "))
+}
+
+// sameFile returns true if both paths point to the same file
+func sameFile(a, b string) bool {
+	ai, errA := os.Stat(a)
+	bi, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(ai, bi)
+}
@@ -0,0 +1,181 @@
+package assembler
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// writeFiles creates files in a temp dir and returns the dir
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func assembleFile(t *testing.T, name string, opts Options) (*Program, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	opts.Name = name
+	return Assemble(f, opts)
+}
+
+func TestAssembleInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.asm":        ".include \"lib/consts.asm\"\n@ROWS\n.include \"os.asm\"\n0;JMP\n",
+		"lib/consts.asm":  ".equ ROWS 32\n.include \"more.asm\"\n",
+		"lib/more.asm":    "@1\n",
+		"sys/os.asm":      "@SCREEN\n",
+		"other/os.asm":    "@KBD\n",
+		"other/extra.asm": "@2\n",
+	})
+	want := []string{"@1", "@ROWS", "@SCREEN", "0;JMP"}
+	wantFiles := []string{"main.asm", "lib/consts.asm", "lib/more.asm", "sys/os.asm"}
+
+	prog, err := assembleFile(t, filepath.Join(dir, "main.asm"), Options{
+		IncludeDirs: []string{filepath.Join(dir, "sys"), filepath.Join(dir, "other")},
+	})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(prog.Instructions) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v", len(prog.Instructions), len(want))
+		return
+	}
+	for i, instr := range prog.Instructions {
+		if instr.Text != want[i] {
+			t.Errorf("Instruction %v: actual %q; want %q", i, instr.Text, want[i])
+		}
+	}
+	if len(prog.Sources) != len(wantFiles) {
+		t.Errorf("Sources len: %v; want len: %v", len(prog.Sources), len(wantFiles))
+		return
+	}
+	for i, src := range prog.Sources {
+		if src.Name != filepath.Join(dir, wantFiles[i]) {
+			t.Errorf("Source %v: actual %v; want %v", i, src.Name, wantFiles[i])
+		}
+	}
+}
+
+func TestAssembleIncludeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle.asm":   ".include \"a.asm\"\n",
+		"a.asm":       "@1\n.include \"b.asm\"\n",
+		"b.asm":       ".include \"a.asm\"\n",
+		"missing.asm": ".include \"none.asm\"\n",
+		"quotes.asm":  ".include none.asm\n",
+		"nested.asm":  "@1\n.include \"wrong.asm\"\n",
+		"wrong.asm":   "(1L)\n",
+	})
+
+	testCases := []struct {
+		file string
+		want string
+	}{
+		{file: "cycle.asm", want: "Include cycle"},
+		{file: "missing.asm", want: "is not found"},
+		{file: "quotes.asm", want: "quoted string"},
+		{file: "nested.asm", want: "(included from " + filepath.Join(dir, "nested.asm") + ":2:1)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.file, func(t *testing.T) {
+			prog, _ := assembleFile(t, filepath.Join(dir, tc.file), Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+				return
+			}
+			err := prog.Diagnostics[0]
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("Error %q does not contain %q", err, tc.want)
+			}
+			var pe *parser.ParseError
+			if !errors.As(err, &pe) {
+				t.Errorf("Error is not ParseError: %v", err)
+			}
+		})
+	}
+}
+
+func TestAssembleFiles(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.asm": ".equ N 5\n@LIB\n0;JMP\n",
+		"b.asm": "(LIB)\n@N\n@i\n",
+		"c.asm": "@i\nD=X\n",
+	})
+	names := []string{filepath.Join(dir, "a.asm"), filepath.Join(dir, "b.asm"), filepath.Join(dir, "c.asm")}
+	wantValues := map[int]int{0: 2, 2: 5, 3: 16, 4: 16}
+
+	prog, err := AssembleFiles(names, Options{})
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != 1 {
+		t.Errorf("Error is not ErrorList with 1 error: %v", err)
+		return
+	}
+	var ee *code.EncoderError
+	if !errors.As(list[0], &ee) || ee.Loc.File != names[2] || ee.Loc.Line != 2 {
+		t.Errorf("Error is not in %s:2: %v", names[2], list[0])
+	}
+
+	for addr, want := range wantValues {
+		if actual := prog.Words[addr].Value(); actual != want {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, want)
+		}
+	}
+	if len(prog.Sources) != len(names) {
+		t.Errorf("Sources len: %v; want len: %v", len(prog.Sources), len(names))
+	}
+}
+
+func TestAssembleFilesBoundaries(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.asm":   "(A)\n(.loop)\n@.loop\n.if 1\n",
+		"b.asm":   "(.loop)\n@1\n",
+		"c.asm":   "(C)\n.include \"inc.asm\"\n@.x\n(.x)\n",
+		"inc.asm": "(INC)\n@0\n",
+	})
+	names := []string{filepath.Join(dir, "a.asm"), filepath.Join(dir, "b.asm"), filepath.Join(dir, "c.asm")}
+	// Local labels of b.asm are not in the scope of a.asm, and ".if" of a.asm does not
+	// take b.asm. The scope of c.asm is restored after the include
+	wantErrs := []parser.Location{{File: names[0], Line: 4}, {File: names[1], Line: 1}}
+
+	prog, err := AssembleFiles(names, Options{})
+	var list ErrorList
+	if !errors.As(err, &list) || len(list) != len(wantErrs) {
+		t.Fatalf("Error is not ErrorList with %d errors: %v", len(wantErrs), err)
+	}
+	for i, want := range wantErrs {
+		var pe *parser.ParseError
+		if !errors.As(list[i], &pe) || pe.Loc.File != want.File || pe.Loc.Line != want.Line {
+			t.Errorf("Error is not in %s:%d: %v", want.File, want.Line, list[i])
+		}
+	}
+	if !prog.Symbols.Exists("C.x") {
+		t.Errorf("Local label C.x is not defined")
+	}
+}
+
+func TestAssembleFilesNotFound(t *testing.T) {
+	if _, err := AssembleFiles([]string{filepath.Join(t.TempDir(), "none.asm")}, Options{}); err == nil {
+		t.Errorf("Error was not arisen as expected")
+	}
+}
@@ -0,0 +1,111 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+const maxAValue = 1<<15 - 1
+
+// LinkError is an error of linking objects
+type LinkError struct {
+	Msg string
+}
+
+func (e *LinkError) Error() string {
+	return e.Msg
+}
+
+// Link places objects in ROM one after another in the given order, resolves symbols
+// across them and allocates variables. Variables with the same name are shared by
+// all objects. Like Assemble, Link collects all errors and returns them as ErrorList
+func Link(objs []*Object, opts Options) (*Program, error) {
+	st := opts.Symbols
+	if st == nil {
+		st = code.NewSymbolTable()
+	}
+	prog := &Program{Symbols: st}
+	diag := &diagnostics{max: opts.MaxErrors}
+	linkErr := func(format string, a ...interface{}) {
+		diag.add(&LinkError{Msg: fmt.Sprintf(format, a...)})
+	}
+
+	bases := make([]int, len(objs))
+	size := 0
+	for i, o := range objs {
+		bases[i] = size
+		size += len(o.Code)
+	}
+	if size > code.ROMSize {
+		linkErr("Linked program has %d words, but ROM size is %d", size, code.ROMSize)
+		prog.Diagnostics = diag.errs
+		return prog, diag.err()
+	}
+
+	// owners are module names of exported labels
+	owners := make(map[string]string)
+	for i, o := range objs {
+		for _, e := range o.Exports {
+			if owner, ok := owners[e.Name]; ok {
+				linkErr("Symbol '%s' is exported by modules %s and %s", e.Name, owner, o.Name)
+				continue
+			}
+			if _, err := st.AddLabel(e.Name, bases[i]+e.Addr); err != nil {
+				linkErr("Module %s: %v", o.Name, err)
+				continue
+			}
+			owners[e.Name] = o.Name
+		}
+	}
+	for _, o := range objs {
+		for _, v := range o.Vars {
+			if owner, ok := owners[v]; ok {
+				linkErr("Variable '%s' of module %s is a label exported by module %s", v, o.Name, owner)
+				continue
+			}
+			if st.Exists(v) {
+				continue
+			}
+			if _, err := st.AddVar(v); err != nil {
+				linkErr("Module %s: %v", o.Name, err)
+			}
+		}
+	}
+
+	prog.Words = make([]code.Word, 0, size)
+	for i, o := range objs {
+		words := append([]code.Word(nil), o.Code...)
+		imports := make(map[string]bool, len(o.Imports))
+		for _, name := range o.Imports {
+			imports[name] = true
+			if _, ok := owners[name]; !ok {
+				linkErr("Symbol '%s' imported by module %s is not exported by any module", name, o.Name)
+			}
+		}
+
+		for _, r := range o.Relocs {
+			value := bases[i] + r.Addend
+			if r.Symbol != "" {
+				if imports[r.Symbol] && owners[r.Symbol] == "" {
+					continue
+				}
+				addr, err := st.Get(r.Symbol)
+				if err != nil {
+					linkErr("Module %s: %v", o.Name, err)
+					continue
+				}
+				value = addr + r.Addend
+			}
+			if value < 0 || value > maxAValue {
+				linkErr("Module %s: value %d at address %d is out of bound 0..%d", o.Name, value, r.Addr, maxAValue)
+				continue
+			}
+			words[r.Addr] = code.Word(value)
+		}
+		prog.Words = append(prog.Words, words...)
+	}
+
+	prog.Diagnostics = diag.errs
+	return prog, diag.err()
+}
@@ -0,0 +1,263 @@
+package assembler
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func assembleObject(t *testing.T, name, asm string) *Object {
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: name + ".asm", Relocatable: true})
+	if err != nil {
+		t.Fatalf("Error from Assemble: %v", err)
+	}
+	obj, err := prog.Object(name)
+	if err != nil {
+		t.Fatalf("Error from Object: %v", err)
+	}
+	return obj
+}
+
+func TestObject(t *testing.T) {
+	asm := `
+		.export START, END
+		.import MULT
+		.equ N 3
+	(START)
+		@N
+		D=A
+		@END-1
+		@MULT
+		@i
+		@i+1
+		@SCREEN
+	(END)
+		@START
+	`
+	wantRelocs := []Reloc{
+		{Addr: 2, Addend: 6},
+		{Addr: 3, Symbol: "MULT"},
+		{Addr: 4, Symbol: "i"},
+		{Addr: 5, Symbol: "i", Addend: 1},
+		{Addr: 7, Addend: 0},
+	}
+	wantExports := []Export{{Name: "START", Addr: 0}, {Name: "END", Addr: 7}}
+
+	obj := assembleObject(t, "main", asm)
+	if !reflect.DeepEqual(obj.Relocs, wantRelocs) {
+		t.Errorf("Relocs: actual %v; want %v", obj.Relocs, wantRelocs)
+	}
+	if !reflect.DeepEqual(obj.Exports, wantExports) {
+		t.Errorf("Exports: actual %v; want %v", obj.Exports, wantExports)
+	}
+	if !reflect.DeepEqual(obj.Imports, []string{"MULT"}) {
+		t.Errorf("Imports: actual %v; want [MULT]", obj.Imports)
+	}
+	if !reflect.DeepEqual(obj.Vars, []string{"i"}) {
+		t.Errorf("Vars: actual %v; want [i]", obj.Vars)
+	}
+	if obj.Code[0].Value() != 3 || obj.Code[6].Value() != 16384 {
+		t.Errorf("Not relocated words: actual %v, %v; want 3, 16384", obj.Code[0].Value(), obj.Code[6].Value())
+	}
+
+	var buf bytes.Buffer
+	if err := obj.WriteJSON(&buf); err != nil {
+		t.Fatalf("Error from WriteJSON: %v", err)
+	}
+	read, err := ReadObject(&buf)
+	if err != nil {
+		t.Fatalf("Error from ReadObject: %v", err)
+	}
+	if !reflect.DeepEqual(read, obj) {
+		t.Errorf("Read object: actual %+v; want %+v", read, obj)
+	}
+}
+
+func TestObjectErrors(t *testing.T) {
+	testCases := []string{
+		"(A)\n@A*2\n",
+		"@0\n@0\n(START)\n@START&7\n",
+		"(A)\n@-A\n",
+		"(A)\n@2-A\n",
+		"(A)\n@A+A\n",
+		"(A)\n@A-i\n",
+		"(A)\n.equ B A|1\n@B\n",
+		"@i+j\n",
+		".export X\n",
+		".import X\n(X)\n",
+		".export X\n.import X\n(X)\n",
+	}
+
+	for _, asm := range testCases {
+		t.Run(asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(asm), Options{Relocatable: true})
+			if len(prog.Diagnostics) == 0 {
+				t.Errorf("No errors")
+			}
+		})
+	}
+}
+
+func TestReadObjectError(t *testing.T) {
+	testCases := []string{
+		"",
+		`{"version": 2}`,
+		`{"version": 1, "code": [0], "relocs": [{"addr": 1}]}`,
+		`{"version": 1, "code": [0], "exports": [{"name": "A", "addr": 2}]}`,
+	}
+
+	for _, s := range testCases {
+		t.Run(s, func(t *testing.T) {
+			if _, err := ReadObject(strings.NewReader(s)); err == nil {
+				t.Errorf("No error")
+			}
+		})
+	}
+}
+
+func TestLink(t *testing.T) {
+	main := assembleObject(t, "main", `
+		.import MULT, DONE
+		@x
+		M=1
+		@MULT
+		0;JMP
+	(LOOP)
+		@LOOP
+		0;JMP
+	`)
+	mult := assembleObject(t, "mult", `
+		.export MULT, DONE
+	(MULT)
+		@y
+		M=0
+		@x
+		D=M
+	(DONE)
+		@DONE+1
+	`)
+	want := map[int]int{0: 16, 2: 6, 4: 4, 6: 17, 8: 16, 10: 11}
+
+	prog, err := Link([]*Object{main, mult}, Options{})
+	if err != nil {
+		t.Fatalf("Error from Link: %v", err)
+	}
+	if len(prog.Words) != 11 {
+		t.Fatalf("Words len: %v; want len: %v", len(prog.Words), 11)
+	}
+	for addr, v := range want {
+		if actual := prog.Words[addr].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, v)
+		}
+	}
+	if addr, _ := prog.Symbols.Get("DONE"); addr != 10 {
+		t.Errorf("DONE: actual %v; want %v", addr, 10)
+	}
+}
+
+func TestLinkLabelConstants(t *testing.T) {
+	first := assembleObject(t, "first", "@0\n@1\n")
+	second := assembleObject(t, "second", `
+	(START)
+		@START
+	(END)
+		.equ HERE START
+		.equ NEXT HERE+1
+		.equ SIZE END-START
+		@HERE
+		@NEXT
+		@SIZE
+	`)
+	want := []int{0, 1, 2, 2, 3, 1}
+
+	prog, err := Link([]*Object{first, second}, Options{})
+	if err != nil {
+		t.Fatalf("Error from Link: %v", err)
+	}
+	actual := make([]int, 0, len(prog.Words))
+	for _, w := range prog.Words {
+		actual = append(actual, w.Value())
+	}
+	if !reflect.DeepEqual(actual, want) {
+		t.Errorf("Actual: %v; want %v", actual, want)
+	}
+}
+
+func TestLinkAtBase(t *testing.T) {
+	first := assembleObject(t, "first", strings.Repeat("@0\n", 8))
+	second := assembleObject(t, "second", `
+		.import EXT
+		@0
+		@0
+	(START)
+		@START+1
+		@1+START
+		@START-1
+		@(START+4)-(1+2)
+		@END-START
+		@EXT+2
+		@x-1
+	(END)
+	`)
+	third := assembleObject(t, "third", ".export EXT\n(EXT)\n@0\n")
+	// START is at 2 in the second module and the module starts at 8
+	want := []int{11, 11, 9, 11, 7, 19, 15}
+
+	prog, err := Link([]*Object{first, second, third}, Options{})
+	if err != nil {
+		t.Fatalf("Error from Link: %v", err)
+	}
+	for i, v := range want {
+		if actual := prog.Words[10+i].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", 10+i, actual, v)
+		}
+	}
+}
+
+func TestLinkErrors(t *testing.T) {
+	testCases := []struct {
+		name    string
+		objs    []string
+		wantMsg []string
+	}{
+		{
+			name:    "duplicate",
+			objs:    []string{".export A\n(A)\n@A\n", ".export A\n(A)\n@A\n"},
+			wantMsg: []string{"mod0", "mod1", "'A'"},
+		},
+		{
+			name:    "unresolved",
+			objs:    []string{".import B\n@B\n"},
+			wantMsg: []string{"mod0", "'B'"},
+		},
+		{
+			name:    "var is label",
+			objs:    []string{"@A\n", ".export A\n(A)\n@A\n"},
+			wantMsg: []string{"mod0", "mod1", "'A'"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			objs := make([]*Object, 0, len(tc.objs))
+			for i, asm := range tc.objs {
+				objs = append(objs, assembleObject(t, "mod"+string(rune('0'+i)), asm))
+			}
+			prog, _ := Link(objs, Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Fatalf("Diagnostics len: %v; want len: %v", len(prog.Diagnostics), 1)
+			}
+			var le *LinkError
+			if !errors.As(prog.Diagnostics[0], &le) {
+				t.Fatalf("Error is not LinkError: %v", prog.Diagnostics[0])
+			}
+			for _, s := range tc.wantMsg {
+				if !strings.Contains(le.Msg, s) {
+					t.Errorf("Error %q does not contain %q", le.Msg, s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package assembler
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const (
+	listingHeader = "ADDR   BINARY            HEX    LINE  SOURCE"
+	// listingIndent is the width of address, binary and hex columns
+	listingIndent = 31
+	// expansionPrefix starts instructions expanded from a macro call
+	expansionPrefix = "+ "
+)
+
+// WriteListing writes every source line with the ROM address, the encoded word
+// in binary and hex and the source text itself. Labels are shown with their addresses.
+// Instructions expanded from a macro call are shown below the call. Data initialization
+// code at the program start is shown below its directive before other lines
+func (p *Program) WriteListing(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	lw := listingWriter{w: bw, prog: p, printed: make(map[string]int)}
+
+	fmt.Fprintln(bw, listingHeader)
+	for addr := 0; addr < len(p.Instructions); {
+		site := p.Instructions[addr].site()
+		if end := lw.aheadEnd(addr); end > addr {
+			lw.writeAhead(site, addr, end)
+			addr = end
+			continue
+		}
+		lw.writeIncludesUpTo(site, addr)
+		lw.writeSourceUpTo(site.File, site.Line-1, addr)
+		next := lw.writeSourceUpTo(site.File, site.Line, addr)
+		if next == addr {
+			// The line has been already printed
+			lw.writeExpanded(addr)
+			next++
+		}
+		addr = next
+	}
+	for _, src := range p.Sources {
+		lw.writeSourceUpTo(src.Name, len(src.Lines), len(p.Instructions))
+	}
+	return bw.Flush()
+}
+
+type listingWriter struct {
+	w    *bufio.Writer
+	prog *Program
+	// printed is the number of the last printed line of each file
+	printed map[string]int
+	// file is the name of the file of the last printed line
+	file string
+	// scope is the last printed global label
+	scope string
+}
+
+// writeIncludesUpTo writes lines of including files up to include directives of loc,
+// so lines of an included file follow its include directive
+func (lw *listingWriter) writeIncludesUpTo(loc parser.Location, addr int) {
+	if loc.Parent == nil {
+		return
+	}
+	lw.writeIncludesUpTo(*loc.Parent, addr)
+	lw.writeSourceUpTo(loc.Parent.File, loc.Parent.Line, addr)
+}
+
+// writeSourceUpTo writes not printed lines of the file up to the line lineNum (included).
+// addr is the address of the next instruction. It returns the address of the next
+// instruction after the written lines
+func (lw *listingWriter) writeSourceUpTo(file string, lineNum, addr int) int {
+	src := lw.prog.source(file)
+	if src == nil {
+		return addr
+	}
+	instrs := lw.prog.Instructions
+	for n := lw.printed[file] + 1; n <= lineNum && n <= len(src.Lines); n++ {
+		// Lines of several files are marked with the file name
+		if file != lw.file && len(lw.prog.Sources) > 1 {
+			fmt.Fprintf(lw.w, "%*s%5s  ; %s\n", listingIndent, "", "", file)
+		}
+		lw.file = file
+
+		text := src.Lines[n-1]
+		trimmed := strings.TrimSpace(text)
+		end := addr
+		for end < len(instrs) && instrs[end].site().Line == n && instrs[end].site().File == file {
+			end++
+		}
+
+		switch {
+		case end == addr+1 && instrs[addr].Loc.Macro == "":
+			word := lw.prog.Words[addr]
+			fmt.Fprintf(lw.w, "%05d  %v  %04X  %5d  %s\n", addr, word, uint16(word), n, text)
+		case end > addr:
+			fmt.Fprintf(lw.w, "%*s%5d  %s\n", listingIndent, "", n, text)
+			for a := addr; a < end; a++ {
+				lw.writeExpanded(a)
+			}
+		case parser.IsLabelLine(trimmed) && lw.isLabel(trimmed):
+			fmt.Fprintf(lw.w, "%05d%*s%5d  %s\n", addr, listingIndent-5, "", n, text)
+		default:
+			fmt.Fprintf(lw.w, "%*s%5d  %s\n", listingIndent, "", n, text)
+		}
+		lw.printed[file] = n
+		addr = end
+	}
+	return addr
+}
+
+// isLabel returns true if the line defines a label of the program. Lines of macro
+// definitions look like labels, but they are not in the symbol table.
+// A global label also becomes the scope of next local labels
+func (lw *listingWriter) isLabel(line string) bool {
+	label, err := parser.NewLabelParser().Parse(line)
+	if err != nil {
+		return false
+	}
+	name := label.Value
+	if label.IsLocal() {
+		name = lw.scope + name
+	}
+	ok := lw.prog.Symbols.Kinds[name] == code.LabelSymbol && lw.prog.Symbols.Exists(name)
+	if ok && !label.IsLocal() {
+		lw.scope = name
+	}
+	return ok
+}
+
+// aheadEnd returns the end of instructions of the source line at addr if the line
+// follows lines of next instructions like data initialization code at the program start.
+// Otherwise it returns addr
+func (lw *listingWriter) aheadEnd(addr int) int {
+	instrs := lw.prog.Instructions
+	site := instrs[addr].site()
+	end := addr
+	for end < len(instrs) && instrs[end].site().Line == site.Line && instrs[end].site().File == site.File {
+		end++
+	}
+	if end == len(instrs) || instrs[end].site().File != site.File || instrs[end].site().Line >= site.Line {
+		return addr
+	}
+	return end
+}
+
+// writeAhead writes the source line of instructions from addr to end before their
+// turn in the file. The line is written again in its turn without instructions
+func (lw *listingWriter) writeAhead(site parser.Location, addr, end int) {
+	if src := lw.prog.source(site.File); src != nil && site.Line <= len(src.Lines) {
+		fmt.Fprintf(lw.w, "%*s%5d  %s\n", listingIndent, "", site.Line, src.Lines[site.Line-1])
+	}
+	for a := addr; a < end; a++ {
+		lw.writeExpanded(a)
+	}
+}
+
+// writeExpanded writes the instruction without its source line
+func (lw *listingWriter) writeExpanded(addr int) {
+	word := lw.prog.Words[addr]
+	text := lw.prog.Instructions[addr].Text
+	fmt.Fprintf(lw.w, "%05d  %v  %04X  %5s  %s%s\n", addr, word, uint16(word), "", expansionPrefix, text)
+}
+
+// source returns the source file by its name or nil if there is no such file
+func (p *Program) source(name string) *SourceFile {
+	for i := range p.Sources {
+		if p.Sources[i].Name == name {
+			return &p.Sources[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package assembler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteListing(t *testing.T) {
+	asm := "// Comment\n(START)\n  @START // Loop\n\n  0;JMP\n"
+	want := []string{
+		listingHeader,
+		"                                   1  // Comment",
+		"00000                              2  (START)",
+		"00000  0000000000000000  0000      3    @START // Loop",
+		"                                   4  ",
+		"00001  1110101010000111  EA87      5    0;JMP",
+	}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: "prog.asm"})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	sb := strings.Builder{}
+	if err := prog.WriteListing(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	actual := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v", len(actual), len(want))
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine != want[i] {
+			t.Errorf("Line %v, Actual %q; want %q", i, actualLine, want[i])
+		}
+	}
+}
+
+func TestWriteListingMacro(t *testing.T) {
+	asm := ".macro M x\n(L)\n  @x\n.endm\n  M 5\n"
+	want := []string{
+		listingHeader,
+		"                                   1  .macro M x",
+		"                                   2  (L)",
+		"                                   3    @x",
+		"                                   4  .endm",
+		"                                   5    M 5",
+		"00000  0000000000000101  0005         + @5",
+	}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: "prog.asm"})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	sb := strings.Builder{}
+	if err := prog.WriteListing(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	actual := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v\n%s", len(actual), len(want), sb.String())
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine != want[i] {
+			t.Errorf("Line %v, Actual %q; want %q", i, actualLine, want[i])
+		}
+	}
+}
+
+func TestWriteListingPseudo(t *testing.T) {
+	asm := "goto END\n(END)\n"
+	want := []string{
+		listingHeader,
+		"                                   1  goto END",
+		"00000  0000000000000010  0002         + @END",
+		"00001  1110101010000111  EA87         + 0;JMP",
+		"00002                              2  (END)",
+	}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: "prog.asm", Pseudo: true})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	sb := strings.Builder{}
+	if err := prog.WriteListing(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	actual := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v\n%s", len(actual), len(want), sb.String())
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine != want[i] {
+			t.Errorf("Line %v, Actual %q; want %q", i, actualLine, want[i])
+		}
+	}
+}
+
+func TestWriteListingData(t *testing.T) {
+	asm := "@TABLE\n.data TABLE\n.word 5\n"
+	want := []string{
+		listingHeader,
+		"                                   3  .word 5",
+		"00000  0000000000000101  0005         + @5",
+		"00001  1110110000010000  EC10         + D=A",
+		"00002  0000000000010000  0010         + @TABLE",
+		"00003  1110001100001000  E308         + M=D",
+		"00004  0000000000010000  0010      1  @TABLE",
+		"                                   2  .data TABLE",
+		"                                   3  .word 5",
+	}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: "prog.asm"})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	sb := strings.Builder{}
+	if err := prog.WriteListing(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	actual := strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n")
+	if len(actual) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v\n%s", len(actual), len(want), sb.String())
+		return
+	}
+	for i, actualLine := range actual {
+		if actualLine != want[i] {
+			t.Errorf("Line %v, Actual %q; want %q", i, actualLine, want[i])
+		}
+	}
+}
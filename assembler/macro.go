@@ -0,0 +1,134 @@
+package assembler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const (
+	macroDirective = "macro"
+	endmDirective  = "endm"
+	// maxMacroDepth is the max number of nested macro calls
+	maxMacroDepth = 64
+	// maxMacroLines is the max number of lines produced by all macro calls. It stops
+	// recursive macros that are not too deep but expand exponentially
+	maxMacroLines = code.ROMSize
+)
+
+// macro is defined as ".macro NAME arg1 arg2 ... .endm". Labels defined in the body
+// are local: they get a unique name in every expansion
+type macro struct {
+	name   string
+	params []string
+	body   []Line
+	labels map[string]bool
+}
+
+// splitMacroArgs splits parameters of a macro definition or arguments of a call.
+// They are separated by commas or spaces
+func splitMacroArgs(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+}
+
+// defineMacro reads the macro body up to ".endm" from the input. The body is read
+// even if the definition is wrong, so its lines are not assembled
+func (p *preprocessor) defineMacro(line Line, args string) error {
+	fields := splitMacroArgs(args)
+	m := &macro{labels: make(map[string]bool)}
+	if len(fields) > 0 {
+		m.name, m.params = fields[0], fields[1:]
+	}
+
+	labelParser := parser.NewLabelParser()
+	for {
+		bodyLine, err := p.nextLine()
+		if err != nil {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Macro '%s' has no '.%s'", m.name, endmDirective)}
+		}
+		if parser.IsDirectiveLine(bodyLine.Text) {
+			d, err := p.dirParser.Parse(bodyLine.Text)
+			if err == nil && d.Name == endmDirective {
+				break
+			}
+			if err == nil && d.Name == macroDirective {
+				p.diag.add(bodyLine.locate(&parser.ParseError{Pos: 1, Msg: "Macro cannot be defined inside another macro"}))
+				continue
+			}
+		}
+		if parser.IsLabelLine(bodyLine.Text) {
+			if label, err := labelParser.Parse(bodyLine.Text); err == nil {
+				m.labels[label.Value] = true
+			}
+		}
+		m.body = append(m.body, bodyLine)
+	}
+
+	if len(fields) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: "Macro name is expected"}
+	}
+	for _, name := range fields {
+		if !parser.IsSymbolName(name) {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong name '%s' in macro definition", name)}
+		}
+	}
+	if _, ok := p.macros[m.name]; ok {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Macro '%s' is already defined", m.name)}
+	}
+	p.macros[m.name] = m
+	return nil
+}
+
+// expandMacro puts the macro body with substituted arguments on top of the frames
+func (p *preprocessor) expandMacro(m *macro, call Line, args string) error {
+	values := splitMacroArgs(args)
+	if len(values) != len(m.params) {
+		return &parser.ParseError{
+			Pos: 1,
+			Msg: fmt.Sprintf("Macro '%s' expects %d arguments, but %d are given", m.name, len(m.params), len(values)),
+		}
+	}
+
+	depth := 0
+	for loc := &call.Loc; loc != nil && loc.Macro != ""; loc = loc.Parent {
+		depth++
+	}
+	if depth >= maxMacroDepth {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Macro calls are nested deeper than %d", maxMacroDepth)}
+	}
+	if p.macroLines+len(m.body) > maxMacroLines {
+		return &code.EncoderError{
+			Msg: fmt.Sprintf("Macro calls produce more than %d lines, it is more than ROM can hold", maxMacroLines),
+		}
+	}
+	p.macroLines += len(m.body)
+
+	p.expansions++
+	subst := make(map[string]string, len(m.params)+len(m.labels))
+	for i, param := range m.params {
+		subst[param] = values[i]
+	}
+	for label := range m.labels {
+		subst[label] = fmt.Sprintf("%s$%s.%d", m.name, label, p.expansions)
+	}
+
+	parent := call.Loc
+	expanded := make([]Line, 0, len(m.body))
+	for _, bodyLine := range m.body {
+		text := parser.ReplaceSymbols(bodyLine.Text, func(name string) string {
+			if v, ok := subst[name]; ok {
+				return v
+			}
+			return name
+		})
+		loc := bodyLine.Loc
+		loc.Macro = m.name
+		loc.Parent = &parent
+		expanded = append(expanded, Line{Text: text, Loc: loc})
+	}
+	p.frames = append(p.frames, frame{lines: expanded})
+	return nil
+}
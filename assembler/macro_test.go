@@ -0,0 +1,129 @@
+package assembler
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func TestAssembleMacro(t *testing.T) {
+	asm := `
+		.macro JMPZ addr   // jump if D is zero
+		@addr
+		D;JEQ
+		.endm
+
+		.macro WAIT
+		(LOOP)
+		@LOOP
+		0;JMP
+		.endm
+
+		JMPZ END
+		WAIT
+		WAIT
+	(END)
+		JMPZ SCREEN+1
+	`
+	want := []string{"@END", "D;JEQ", "@WAIT$LOOP.2", "0;JMP", "@WAIT$LOOP.3", "0;JMP", "@SCREEN+1", "D;JEQ"}
+	wantValues := map[int]int{0: 6, 2: 2, 4: 4, 6: code.ScreenAddr + 1}
+
+	prog, err := Assemble(strings.NewReader(asm), Options{})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if len(prog.Instructions) != len(want) {
+		t.Errorf("Actual len: %v; want len: %v", len(prog.Instructions), len(want))
+		return
+	}
+	for i, instr := range prog.Instructions {
+		if instr.Text != want[i] {
+			t.Errorf("Instruction %v: actual %q; want %q", i, instr.Text, want[i])
+		}
+	}
+	for addr, v := range wantValues {
+		if actual := prog.Words[addr].Value(); actual != v {
+			t.Errorf("Word %v: actual %v; want %v", addr, actual, v)
+		}
+	}
+}
+
+func TestAssembleMacroErrors(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		wantLine int
+	}{
+		{asm: ".macro M a\n@a\n.endm\nM\n", wantLine: 4},
+		{asm: ".macro M\n@1\n", wantLine: 1},
+		{asm: "@1\n.endm\n", wantLine: 2},
+		{asm: ".macro M\n.endm\n.macro M\n.endm\n", wantLine: 3},
+		{asm: ".macro M\n.macro N\n.endm\n", wantLine: 2},
+		{asm: ".macro 1M\n.endm\n", wantLine: 1},
+		{asm: ".macro\n@1\n.endm\n", wantLine: 1},
+		{asm: ".macro M\nM\n.endm\nM\n", wantLine: 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{})
+			if len(prog.Diagnostics) != 1 {
+				t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+				return
+			}
+			var pe *parser.ParseError
+			if !errors.As(prog.Diagnostics[0], &pe) {
+				t.Errorf("Error is not ParseError: %v", prog.Diagnostics[0])
+				return
+			}
+			if pe.Loc.Line != tc.wantLine {
+				t.Errorf("Error %v: actual line %v; want %v", pe, pe.Loc.Line, tc.wantLine)
+			}
+		})
+	}
+}
+
+func TestAssembleMacroErrorLocation(t *testing.T) {
+	asm := ".macro M x\n  @x\n  D=Q\n.endm\n@0\n    M 1\n"
+	want := parser.Location{File: "prog.asm", Line: 3, Col: 3, Source: "  D=Q", Macro: "M"}
+	wantParent := parser.Location{File: "prog.asm", Line: 6, Col: 5, Source: "    M 1"}
+
+	prog, _ := Assemble(strings.NewReader(asm), Options{Name: "prog.asm"})
+	if len(prog.Diagnostics) != 1 {
+		t.Errorf("Diagnostics: %v; want 1 error", prog.Diagnostics)
+		return
+	}
+	var ee *code.EncoderError
+	if !errors.As(prog.Diagnostics[0], &ee) {
+		t.Errorf("Error is not EncoderError: %v", prog.Diagnostics[0])
+		return
+	}
+	actual := ee.Loc
+	if actual.Parent == nil || *actual.Parent != wantParent {
+		t.Errorf("Actual parent: %+v, want: %+v", actual.Parent, wantParent)
+		return
+	}
+	actual.Parent = nil
+	if actual != want {
+		t.Errorf("Actual: %+v, want: %+v", actual, want)
+	}
+}
+
+func TestAssembleMacroRecursion(t *testing.T) {
+	asm := ".macro M\n@1\nM\nM\n.endm\nM\n"
+	prog, err := Assemble(strings.NewReader(asm), Options{MaxErrors: 0})
+	if err == nil {
+		t.Errorf("No error for recursive macro")
+		return
+	}
+	for _, err := range prog.Diagnostics {
+		var ee *code.EncoderError
+		if errors.As(err, &ee) {
+			return
+		}
+	}
+	t.Errorf("No error of the limit of lines in %d errors", len(prog.Diagnostics))
+}
@@ -0,0 +1,240 @@
+package assembler
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// module keeps what the label pass collects besides labels: constants, exported
+// and imported symbols, data. A relocatable module also collects relocations and variables
+type module struct {
+	consts  constDefs
+	exports []symbolRef
+	imports map[string]Line
+	// locals are references to local labels by full names
+	locals []symbolRef
+	// labelConsts are constants computed from labels
+	labelConsts []symbolRef
+	// labelExprs are expressions of labelConsts. They are relocated like labels
+	labelExprs map[string]parser.Expr
+	// constUses are constants used by directives before the code is placed
+	constUses []symbolRef
+	data      []dataDef
+	// ramLines are directives that allocate RAM
+	ramLines []Line
+
+	relocatable bool
+	relocs      []Reloc
+	vars        []string
+}
+
+// symbolRef is a symbol used in the line
+type symbolRef struct {
+	name string
+	line Line
+}
+
+func newModule() *module {
+	return &module{consts: constDefs{}, imports: make(map[string]Line), labelExprs: make(map[string]parser.Expr)}
+}
+
+// export adds labels of the directive ".export NAME1, NAME2"
+func (m *module) export(args string, line Line) error {
+	names := splitMacroArgs(args)
+	if len(names) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: "Exported symbol is expected"}
+	}
+	for _, name := range names {
+		if !parser.IsSymbolName(name) {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong symbol name '%s'", name)}
+		}
+		m.exports = append(m.exports, symbolRef{name: name, line: line})
+	}
+	return nil
+}
+
+// importSymbols adds symbols of the directive ".import NAME1, NAME2". They are defined
+// in other modules
+func (m *module) importSymbols(args string, line Line) error {
+	names := splitMacroArgs(args)
+	if len(names) == 0 {
+		return &parser.ParseError{Pos: 1, Msg: "Imported symbol is expected"}
+	}
+	for _, name := range names {
+		if !parser.IsSymbolName(name) {
+			return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong symbol name '%s'", name)}
+		}
+		m.imports[name] = line
+	}
+	return nil
+}
+
+// checkExports adds errors of exported symbols that are not labels of the module
+// and imported symbols that are defined in the module
+func (m *module) checkExports(st *code.SymbolTable, diag *diagnostics) {
+	for _, e := range m.exports {
+		if !st.Exists(e.name) || st.Kinds[e.name] != code.LabelSymbol {
+			diag.add(e.line.locate(&code.EncoderError{Msg: fmt.Sprintf("Exported symbol '%s' is not a label", e.name)}))
+		}
+		if _, ok := m.imports[e.name]; ok {
+			diag.add(e.line.locate(&code.EncoderError{Msg: fmt.Sprintf("Symbol '%s' is both exported and imported", e.name)}))
+		}
+	}
+	for name, line := range m.imports {
+		if m.relocatable && st.Exists(name) {
+			diag.add(line.locate(&code.EncoderError{Msg: fmt.Sprintf("Imported symbol '%s' is defined in the module", name)}))
+		}
+	}
+}
+
+// checkLocals adds errors of references to local labels that are not defined
+func (m *module) checkLocals(st *code.SymbolTable, diag *diagnostics) {
+	for _, l := range m.locals {
+		if !st.Exists(l.name) || st.Kinds[l.name] != code.LabelSymbol {
+			diag.add(l.line.locate(&code.EncoderError{Msg: fmt.Sprintf("Local label '%s' is not defined", l.name)}))
+		}
+	}
+}
+
+// encodeAInstr returns the machine word of A-Instruction. In a relocatable module
+// references to labels, imported symbols and variables are added to relocations
+func (m *module) encodeAInstr(ai parser.AInstruction, addr int, st *code.SymbolTable) (code.Word, error) {
+	if !ai.IsVar && !ai.IsExpr {
+		return code.EncodeAInstr(ai, st)
+	}
+	if !m.relocatable {
+		if _, ok := m.imports[ai.Value]; ok && ai.IsVar && !st.Exists(ai.Value) {
+			return 0, &code.EncoderError{Msg: fmt.Sprintf("Imported symbol '%s' is not defined", ai.Value)}
+		}
+		return code.EncodeAInstr(ai, st)
+	}
+
+	expr, err := parser.ParseExpr(ai.Value)
+	if err != nil {
+		return 0, err
+	}
+	reloc, err := m.relocate(expr, st)
+	if err != nil {
+		return 0, &code.EncoderError{Msg: fmt.Sprintf("Cannot encode %v: %v", ai.Value, err)}
+	}
+	if !reloc.relocated {
+		return code.EncodeAInstr(parser.AInstruction{IsExpr: true, Value: ai.Value}, st)
+	}
+
+	m.relocs = append(m.relocs, Reloc{Addr: addr, Symbol: reloc.symbol, Addend: reloc.addend})
+	if _, ok := m.imports[reloc.symbol]; !ok && reloc.symbol != "" && !m.hasVar(reloc.symbol) {
+		m.vars = append(m.vars, reloc.symbol)
+	}
+	return 0, nil
+}
+
+func (m *module) hasVar(name string) bool {
+	for _, v := range m.vars {
+		if v == name {
+			return true
+		}
+	}
+	return false
+}
+
+// relocation is a value of an expression that is known only after linking:
+// the address of the symbol (or the module if the symbol is empty) plus addend
+type relocation struct {
+	relocated bool
+	symbol    string
+	addend    int
+}
+
+// relocTerm is the part of an expression that is known only after linking: the module
+// address if base is set or the address of an external symbol: an imported symbol or
+// a variable. An absolute expression has no term
+type relocTerm struct {
+	base   bool
+	symbol string
+}
+
+func (t relocTerm) absolute() bool {
+	return !t.base && t.symbol == ""
+}
+
+var errNotRelocatable = errors.New("Expression cannot be relocated, it must be an address plus a constant")
+
+// relocate finds out how the value of the expression depends on the module address
+// and on an external symbol. The expression must be an address plus or minus a constant
+func (m *module) relocate(expr parser.Expr, st *code.SymbolTable) (relocation, error) {
+	term, err := m.relocTerm(expr, st)
+	if err != nil {
+		return relocation{}, err
+	}
+
+	// Labels are evaluated as offsets in the module and the external symbol as zero,
+	// so the value is the addend
+	var lookup func(name string) (int, error)
+	lookup = func(name string) (int, error) {
+		if name == term.symbol {
+			return 0, nil
+		}
+		if e, ok := m.labelExprs[name]; ok {
+			return e.Eval(lookup)
+		}
+		return st.Get(name)
+	}
+	v, err := expr.Eval(lookup)
+	if err != nil || term.absolute() {
+		return relocation{}, err
+	}
+	return relocation{relocated: true, symbol: term.symbol, addend: v}, nil
+}
+
+// relocTerm returns the term of the expression. Only "TERM + CONST", "CONST + TERM" and
+// "TERM - CONST" have a term. A difference of two labels is absolute
+func (m *module) relocTerm(expr parser.Expr, st *code.SymbolTable) (relocTerm, error) {
+	switch e := expr.(type) {
+	case parser.SymbolExpr:
+		name := string(e)
+		if labelExpr, ok := m.labelExprs[name]; ok {
+			return m.relocTerm(labelExpr, st)
+		}
+		switch {
+		case !st.Exists(name) || st.Kinds[name] == code.VarSymbol:
+			return relocTerm{symbol: name}, nil
+		case st.Kinds[name] == code.LabelSymbol:
+			return relocTerm{base: true}, nil
+		}
+		return relocTerm{}, nil
+	case *parser.UnaryExpr:
+		x, err := m.relocTerm(e.X, st)
+		if err == nil && !x.absolute() {
+			err = errNotRelocatable
+		}
+		return relocTerm{}, err
+	case *parser.BinaryExpr:
+		x, err := m.relocTerm(e.X, st)
+		if err != nil {
+			return relocTerm{}, err
+		}
+		y, err := m.relocTerm(e.Y, st)
+		if err != nil {
+			return relocTerm{}, err
+		}
+		switch {
+		case x.absolute() && y.absolute():
+			return relocTerm{}, nil
+		case x.symbol != "" && y.symbol != "" && x.symbol != y.symbol:
+			return relocTerm{}, fmt.Errorf("Expression can use only one imported symbol or variable")
+		case e.Op == "+" && y.absolute():
+			return x, nil
+		case e.Op == "+" && x.absolute():
+			return y, nil
+		case e.Op == "-" && y.absolute():
+			return x, nil
+		case e.Op == "-" && x.base && y.base:
+			return relocTerm{}, nil
+		}
+		return relocTerm{}, errNotRelocatable
+	}
+	return relocTerm{}, nil
+}
@@ -0,0 +1,109 @@
+package assembler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+const objectVersion = 1
+
+// Object is a relocatable module. Code starts from the address 0 and words
+// of relocated A-Instructions are set by the linker
+type Object struct {
+	Version int         `json:"version"`
+	Name    string      `json:"name"`
+	Code    []code.Word `json:"code"`
+	Relocs  []Reloc     `json:"relocs"`
+	// Exports are labels of the module that can be used by other modules
+	Exports []Export `json:"exports"`
+	// Imports are labels that are exported by other modules
+	Imports []string `json:"imports"`
+	// Vars are variables of the module. Variables with the same name are shared by modules
+	Vars []string `json:"vars"`
+}
+
+// Reloc is an A-Instruction at Addr with the value known after linking: the address of
+// Symbol plus Addend. If Symbol is empty, Addend is an address in the module
+type Reloc struct {
+	Addr   int    `json:"addr"`
+	Symbol string `json:"symbol,omitempty"`
+	Addend int    `json:"addend"`
+}
+
+// Export is an exported label with its address in the module
+type Export struct {
+	Name string `json:"name"`
+	Addr int    `json:"addr"`
+}
+
+// Object returns the object of the program assembled with Options.Relocatable
+func (p *Program) Object(name string) (*Object, error) {
+	if p.mod == nil || !p.mod.relocatable {
+		return nil, errors.New("Program is not relocatable")
+	}
+
+	obj := &Object{
+		Version: objectVersion,
+		Name:    name,
+		Code:    p.Words,
+		Relocs:  p.mod.relocs,
+		Exports: []Export{},
+		Imports: []string{},
+		Vars:    p.mod.vars,
+	}
+	if obj.Relocs == nil {
+		obj.Relocs = []Reloc{}
+	}
+	if obj.Vars == nil {
+		obj.Vars = []string{}
+	}
+	for _, e := range p.mod.exports {
+		addr, err := p.Symbols.Get(e.name)
+		if err != nil {
+			return nil, err
+		}
+		obj.Exports = append(obj.Exports, Export{Name: e.name, Addr: addr})
+	}
+	for name := range p.mod.imports {
+		obj.Imports = append(obj.Imports, name)
+	}
+	sort.Strings(obj.Imports)
+	return obj, nil
+}
+
+// WriteJSON writes the object as JSON
+func (o *Object) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(o)
+}
+
+// ReadObject reads an object written by WriteJSON and checks it
+func ReadObject(r io.Reader) (*Object, error) {
+	var o Object
+	if err := json.NewDecoder(r).Decode(&o); err != nil {
+		return nil, fmt.Errorf("Cannot read object: %v", err)
+	}
+	if o.Version != objectVersion {
+		return nil, fmt.Errorf("Unsupported version of object %d", o.Version)
+	}
+	if len(o.Code) > code.ROMSize {
+		return nil, fmt.Errorf("Object has %d words, but ROM size is %d", len(o.Code), code.ROMSize)
+	}
+	for _, r := range o.Relocs {
+		if r.Addr < 0 || r.Addr >= len(o.Code) {
+			return nil, fmt.Errorf("Relocation address %d is out of code", r.Addr)
+		}
+	}
+	for _, e := range o.Exports {
+		if e.Addr < 0 || e.Addr > len(o.Code) {
+			return nil, fmt.Errorf("Address %d of exported symbol '%s' is out of code", e.Addr, e.Name)
+		}
+	}
+	return &o, nil
+}
@@ -0,0 +1,189 @@
+package assembler
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// lineReader returns code lines one by one until io.EOF
+type lineReader interface {
+	readNextCodeLine() (Line, error)
+}
+
+// frame is a source of lines: an included file or expanded lines of a macro
+type frame struct {
+	reader *codeReader
+	// parent is the location of the include directive of the reader
+	parent *parser.Location
+	lines  []Line
+	// conds is the number of open ".if" blocks when the file is started
+	conds int
+}
+
+// preprocessor reads code lines, includes files and expands macros before the label pass.
+// Its errors are added to diag and wrong lines are skipped
+type preprocessor struct {
+	frames      []frame
+	readers     []*codeReader
+	includeDirs []string
+	diag        *diagnostics
+	dirParser   *parser.DirectiveParser
+	macros      map[string]*macro
+	expansions  int
+	reptLines   int
+	macroLines  int
+	// symbols are used to evaluate conditions. Constants are added to them by the label pass
+	symbols *code.SymbolTable
+	// constUses are constants used by conditions and repeats
+	constUses []symbolRef
+	conds     []cond
+	// pseudo enables pseudo-instructions
+	pseudo bool
+}
+
+// newPreprocessor returns a preprocessor reading inputs one by one in the given order
+func newPreprocessor(inputs []*codeReader, includeDirs []string, st *code.SymbolTable, diag *diagnostics) *preprocessor {
+	p := &preprocessor{
+		frames:      make([]frame, 0, len(inputs)),
+		readers:     append([]*codeReader(nil), inputs...),
+		includeDirs: includeDirs,
+		diag:        diag,
+		dirParser:   parser.NewDirectiveParser(),
+		macros:      make(map[string]*macro),
+		symbols:     st,
+	}
+	// The first input is on top of the frames
+	for i := len(inputs) - 1; i >= 0; i-- {
+		p.frames = append(p.frames, frame{reader: inputs[i]})
+	}
+	return p
+}
+
+// nextLine returns the next line of the top frame. Finished frames are removed
+func (p *preprocessor) nextLine() (Line, error) {
+	for len(p.frames) > 0 {
+		f := &p.frames[len(p.frames)-1]
+		if f.reader == nil && len(f.lines) > 0 {
+			line := f.lines[0]
+			f.lines = f.lines[1:]
+			return line, nil
+		}
+		if f.reader != nil {
+			line, err := f.reader.readNextCodeLine()
+			if err == nil {
+				line.Loc.Parent = f.parent
+				return line, nil
+			}
+			if err != io.EOF {
+				p.diag.add(fmt.Errorf("Cannot read %s: %w", f.reader.name, err))
+			}
+			// ".if" blocks of a file must be closed in the file
+			p.closeConds(f.conds)
+		}
+		p.frames = p.frames[:len(p.frames)-1]
+	}
+	return Line{}, io.EOF
+}
+
+// sources returns all read files. A file included several times is returned once
+func (p *preprocessor) sources() []SourceFile {
+	files := make([]SourceFile, 0, len(p.readers))
+	added := make(map[string]bool)
+	for _, r := range p.readers {
+		if !added[r.name] {
+			added[r.name] = true
+			files = append(files, SourceFile{Name: r.name, Lines: r.source})
+		}
+	}
+	return files
+}
+
+// readNextCodeLine returns the next line to be assembled. Definitions of macros are
+// read here, calls of macros are replaced with their bodies, lines excluded by
+// conditional assembly are skipped
+func (p *preprocessor) readNextCodeLine() (Line, error) {
+	for !p.diag.full() {
+		line, err := p.nextLine()
+		if err != nil {
+			p.closeConds(0)
+			return Line{}, err
+		}
+
+		ok, err := p.preprocess(line)
+		if err != nil {
+			p.diag.add(line.locate(err))
+			continue
+		}
+		if !ok {
+			return line, nil
+		}
+	}
+	return Line{}, ErrTooManyErrors
+}
+
+// preprocess executes the line if it is a directive of the preprocessor or a macro call.
+// It returns false if the line must be passed to the label pass. Lines of inactive
+// branches of ".if" are skipped without errors
+func (p *preprocessor) preprocess(line Line) (bool, error) {
+	if parser.IsDirectiveLine(line.Text) {
+		d, err := p.dirParser.Parse(line.Text)
+		if err != nil && !p.active() {
+			return true, nil
+		}
+		if err != nil {
+			return true, err
+		}
+		if ok, err := p.condDirective(line, d); ok || !p.active() {
+			return true, err
+		}
+		switch d.Name {
+		case includeDirective:
+			return true, p.include(line, d.Args)
+		case macroDirective:
+			return true, p.defineMacro(line, d.Args)
+		case endmDirective:
+			return true, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", endmDirective, macroDirective)}
+		case reptDirective:
+			return true, p.repeat(line, d.Args)
+		case endrDirective:
+			return true, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' without '.%s'", endrDirective, reptDirective)}
+		}
+		return false, nil
+	}
+
+	if !p.active() {
+		return true, nil
+	}
+	name, args := parser.SplitArg(parser.StripComment(line.Text))
+	if m, ok := p.macros[name]; ok {
+		return true, p.expandMacro(m, line, args)
+	}
+	if p.pseudo {
+		return p.expandPseudo(line, name)
+	}
+	return false, nil
+}
+
+// expandPseudo puts real instructions of a pseudo-instruction on top of the frames.
+// They are shown in the listing like an expansion of a macro. It returns false if
+// the line is not a pseudo-instruction
+func (p *preprocessor) expandPseudo(line Line, name string) (bool, error) {
+	texts, ok, err := parser.ExpandPseudo(line.Text)
+	if !ok || err != nil {
+		return ok, err
+	}
+
+	parent := line.Loc
+	expanded := make([]Line, 0, len(texts))
+	for _, text := range texts {
+		loc := line.Loc
+		loc.Macro = name
+		loc.Parent = &parent
+		expanded = append(expanded, Line{Text: text, Loc: loc})
+	}
+	p.frames = append(p.frames, frame{lines: expanded})
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package assembler
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// Line is a label or an instruction line without surrounding spaces.
+// Loc.Col points to the first symbol of Text
+type Line struct {
+	Text string
+	Loc  parser.Location
+}
+
+// locate sets the location of the line to ParseError or EncoderError
+func (l Line) locate(err error) error {
+	var pe *parser.ParseError
+	var ee *code.EncoderError
+	switch {
+	case errors.As(err, &pe):
+		pe.Loc = l.Loc
+		if pe.Pos > 1 {
+			pe.Loc.Col += pe.Pos - 1
+		}
+	case errors.As(err, &ee):
+		ee.Loc = l.Loc
+	}
+	return err
+}
+
+// site returns the location the line is placed in the listing and in the source map.
+// Lines of a macro body are placed at the macro call
+func (l Line) site() parser.Location {
+	loc := l.Loc
+	for loc.Macro != "" && loc.Parent != nil {
+		loc = *loc.Parent
+	}
+	return loc
+}
+
+// fileKey identifies a file by its name and the include directive of the file.
+// The same file included twice has two keys
+type fileKey struct {
+	name   string
+	parent *parser.Location
+}
+
+// file returns the key of the file the line belongs to. Lines expanded from a macro
+// belong to the file of the call
+func (l Line) file() fileKey {
+	site := l.site()
+	return fileKey{name: site.File, parent: site.Parent}
+}
+
+type codeReader struct {
+	input     *bufio.Reader
+	name      string
+	LineCount int
+	// source keeps all read lines including comments and empty ones
+	source []string
+}
+
+func newCodeReader(in *bufio.Reader, name string) *codeReader {
+	return &codeReader{input: in, name: name}
+}
+
+func (r *codeReader) readNextLine() (Line, error) {
+	line, err := r.input.ReadString('\n')
+	// The last line may have no line break, so it is returned together with io.EOF
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return Line{}, err
+	}
+	r.LineCount++
+	source := strings.TrimRight(line, "\r\n")
+	r.source = append(r.source, source)
+	normLine := strings.TrimLeft(source, " \t")
+	col := utf8.RuneCountInString(source) - utf8.RuneCountInString(normLine) + 1
+	normLine = strings.TrimRight(normLine, " \t")
+
+	loc := parser.Location{File: r.name, Line: r.LineCount, Col: col, Source: source}
+	return Line{Text: normLine, Loc: loc}, nil
+}
+
+// readNextCodeLine skips all comment lines and empty line and read next instruction or label line
+func (r *codeReader) readNextCodeLine() (Line, error) {
+	for {
+		line, err := r.readNextLine()
+		if err != nil {
+			return Line{}, err
+		}
+		if len(line.Text) > 0 && !parser.IsCommentLine(line.Text) {
+			return line, nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package assembler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const (
+	reptDirective = "rept"
+	endrDirective = "endr"
+	// maxReptLines is the max number of lines produced by all repeats. Every instruction
+	// takes a word of ROM, so a program with more lines cannot be assembled anyway
+	maxReptLines = code.ROMSize
+)
+
+// repeat reads the body of ".rept COUNT [COUNTER] ... .endr" and puts COUNT copies of it
+// on top of the frames. COUNT is a constant expression. COUNTER is a symbol that is
+// replaced with the number of the iteration starting with 0. Labels of the body are
+// unique in every iteration like labels of a macro
+func (p *preprocessor) repeat(line Line, args string) error {
+	body, labels, err := p.readReptBody()
+	if err != nil {
+		return err
+	}
+
+	countArg, counter := parser.SplitArg(args)
+	if countArg == "" {
+		return &parser.ParseError{Pos: 1, Msg: "Number of repeats is expected"}
+	}
+	if counter != "" && !parser.IsSymbolName(counter) {
+		return &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Wrong counter name '%s'", counter)}
+	}
+	p.constUses = append(p.constUses, constRefs(countArg, line)...)
+	count, err := evalConst(countArg, p.symbols)
+	if err != nil {
+		return err
+	}
+	if count < 0 {
+		return &code.EncoderError{Msg: fmt.Sprintf("Number of repeats %d is negative", count)}
+	}
+	if count > maxReptLines || p.reptLines+count*len(body) > maxReptLines {
+		return &code.EncoderError{
+			Msg: fmt.Sprintf("Repeats produce more than %d lines, it is more than ROM can hold", maxReptLines),
+		}
+	}
+	p.reptLines += count * len(body)
+	if len(body) == 0 {
+		return nil
+	}
+
+	parent := line.Loc
+	expanded := make([]Line, 0, count*len(body))
+	for i := 0; i < count; i++ {
+		p.expansions++
+		subst := make(map[string]string, len(labels)+1)
+		for label := range labels {
+			subst[label] = fmt.Sprintf("%s$%s.%d", reptDirective, label, p.expansions)
+		}
+		if counter != "" {
+			subst[counter] = strconv.Itoa(i)
+		}
+
+		for _, bodyLine := range body {
+			text := parser.ReplaceSymbols(bodyLine.Text, func(name string) string {
+				if v, ok := subst[name]; ok {
+					return v
+				}
+				return name
+			})
+			loc := bodyLine.Loc
+			loc.Macro = "." + reptDirective
+			loc.Parent = &parent
+			expanded = append(expanded, Line{Text: text, Loc: loc})
+		}
+	}
+	p.frames = append(p.frames, frame{lines: expanded})
+	return nil
+}
+
+// readReptBody reads lines up to the matching ".endr". Nested repeats are read as
+// a part of the body. It also returns labels defined in the body
+func (p *preprocessor) readReptBody() ([]Line, map[string]bool, error) {
+	var body []Line
+	labels := make(map[string]bool)
+	labelParser := parser.NewLabelParser()
+	for depth := 0; ; {
+		bodyLine, err := p.nextLine()
+		if err != nil {
+			return nil, nil, &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("'.%s' has no '.%s'", reptDirective, endrDirective)}
+		}
+		if parser.IsDirectiveLine(bodyLine.Text) {
+			d, err := p.dirParser.Parse(bodyLine.Text)
+			switch {
+			case err == nil && d.Name == reptDirective:
+				depth++
+			case err == nil && d.Name == endrDirective && depth == 0:
+				return body, labels, nil
+			case err == nil && d.Name == endrDirective:
+				depth--
+			}
+		}
+		if parser.IsLabelLine(bodyLine.Text) && depth == 0 {
+			if label, err := labelParser.Parse(bodyLine.Text); err == nil {
+				labels[label.Value] = true
+			}
+		}
+		body = append(body, bodyLine)
+	}
+}
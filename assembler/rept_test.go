@@ -0,0 +1,112 @@
+package assembler
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func TestAssembleRept(t *testing.T) {
+	testCases := []struct {
+		name string
+		asm  string
+		want []int
+	}{
+		{
+			name: "counter",
+			asm:  ".equ N 3\n.rept N i\n@SCREEN+i*32\n.endr\n",
+			want: []int{code.ScreenAddr, code.ScreenAddr + 32, code.ScreenAddr + 64},
+		},
+		{
+			name: "no counter",
+			asm:  ".rept 2\n@7\n.endr\n@1\n",
+			want: []int{7, 7, 1},
+		},
+		{
+			name: "zero",
+			asm:  ".rept 0\n@7\n.endr\n@1\n",
+			want: []int{1},
+		},
+		{
+			name: "nested",
+			asm:  ".rept 2 i\n.rept 2 j\n@i*10+j\n.endr\n.endr\n",
+			want: []int{0, 1, 10, 11},
+		},
+		{
+			name: "labels",
+			asm:  ".rept 2\n(SKIP)\n@SKIP\n.endr\n",
+			want: []int{0, 1},
+		},
+		{
+			name: "empty",
+			asm:  ".rept 100\n.endr\n@1\n",
+			want: []int{1},
+		},
+		{
+			name: "condition",
+			asm:  ".rept 3 i\n.if i != 1\n@i\n.endif\n.endr\n",
+			want: []int{0, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prog, err := Assemble(strings.NewReader(tc.asm), Options{})
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			actual := make([]int, 0, len(prog.Words))
+			for _, w := range prog.Words {
+				actual = append(actual, w.Value())
+			}
+			if !reflect.DeepEqual(actual, tc.want) {
+				t.Errorf("Actual: %v; want %v", actual, tc.want)
+			}
+		})
+	}
+}
+
+func TestAssembleReptErrors(t *testing.T) {
+	testCases := []struct {
+		asm      string
+		wantLine int
+	}{
+		{asm: "@1\n.rept 2\n@2\n", wantLine: 2},
+		{asm: ".endr\n", wantLine: 1},
+		{asm: ".rept\n@1\n.endr\n", wantLine: 1},
+		{asm: ".rept -1\n@1\n.endr\n", wantLine: 1},
+		{asm: ".rept N\n@1\n.endr\n", wantLine: 1},
+		{asm: ".rept 2 1i\n@1\n.endr\n", wantLine: 1},
+		{asm: ".rept 200\n.rept 200\n@1\n.endr\n.endr\n", wantLine: 2},
+		{asm: ".rept 2\n@1+\n.endr\n", wantLine: 2},
+		{asm: ".rept 1<<61\n@1\n@2\n@3\n@4\n.endr\n", wantLine: 1},
+		{asm: ".rept 1<<40\n.endr\n", wantLine: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.asm, func(t *testing.T) {
+			prog, _ := Assemble(strings.NewReader(tc.asm), Options{})
+			if len(prog.Diagnostics) == 0 {
+				t.Errorf("No errors")
+				return
+			}
+			var actual int
+			var pe *parser.ParseError
+			var ee *code.EncoderError
+			switch err := prog.Diagnostics[0]; {
+			case errors.As(err, &pe):
+				actual = pe.Loc.Line
+			case errors.As(err, &ee):
+				actual = ee.Loc.Line
+			}
+			if actual != tc.wantLine {
+				t.Errorf("Error %v: actual line %v; want %v", prog.Diagnostics[0], actual, tc.wantLine)
+			}
+		})
+	}
+}
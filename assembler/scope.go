@@ -0,0 +1,38 @@
+package assembler
+
+import (
+	"fmt"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// scopedName returns the full name of a local label ".name" in the scope of
+// the global label: "SCOPE.name". It is a regular symbol name, so a local label
+// can be used outside its scope by the full name
+func scopedName(scope, local string) (string, error) {
+	if scope == "" {
+		return "", &parser.ParseError{Pos: 1, Msg: fmt.Sprintf("Local label '%s' has no global label before it", local)}
+	}
+	return scope + local, nil
+}
+
+// localNames replaces names of local labels in the line with their full names in the scope.
+// The full names are added to mod.locals to be checked after the label pass
+func localNames(s, scope string, line Line, mod *module) (string, error) {
+	var err error
+	res := parser.ReplaceSymbols(s, func(name string) string {
+		if !parser.IsLocalName(name) {
+			return name
+		}
+		full, e := scopedName(scope, name)
+		if e != nil {
+			if err == nil {
+				err = e
+			}
+			return name
+		}
+		mod.locals = append(mod.locals, symbolRef{name: full, line: line})
+		return full
+	})
+	return res, err
+}
@@ -0,0 +1,100 @@
+package assembler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const sourceMapVersion = 1
+
+// SourceMap maps ROM addresses to locations in source files and back
+type SourceMap struct {
+	Files []string
+	// Entries[addr] is the location of the instruction at ROM address addr
+	Entries []SourceMapEntry
+}
+
+// SourceMapEntry is the location of an instruction. File is an index in SourceMap.Files
+type SourceMapEntry struct {
+	File int
+	Line int
+	Col  int
+}
+
+// jsonSourceMap is a compact form of SourceMap: every entry is [file, line, col]
+type jsonSourceMap struct {
+	Version int      `json:"version"`
+	Files   []string `json:"files"`
+	Map     [][3]int `json:"map"`
+}
+
+// SourceMap returns the source map of the program instructions
+func (p *Program) SourceMap() *SourceMap {
+	m := &SourceMap{Entries: make([]SourceMapEntry, 0, len(p.Instructions))}
+	fileIdx := make(map[string]int)
+	for _, instr := range p.Instructions {
+		loc := instr.site()
+		idx, ok := fileIdx[loc.File]
+		if !ok {
+			idx = len(m.Files)
+			fileIdx[loc.File] = idx
+			m.Files = append(m.Files, loc.File)
+		}
+		m.Entries = append(m.Entries, SourceMapEntry{File: idx, Line: loc.Line, Col: loc.Col})
+	}
+	return m
+}
+
+// Location returns the location of the instruction at ROM address addr
+func (m *SourceMap) Location(addr int) (parser.Location, bool) {
+	if addr < 0 || addr >= len(m.Entries) {
+		return parser.Location{}, false
+	}
+	e := m.Entries[addr]
+	return parser.Location{File: m.Files[e.File], Line: e.Line, Col: e.Col}, true
+}
+
+// Addr returns the first ROM address of instructions from the line of the file
+func (m *SourceMap) Addr(file string, line int) (int, bool) {
+	for addr, e := range m.Entries {
+		if e.Line == line && m.Files[e.File] == file {
+			return addr, true
+		}
+	}
+	return 0, false
+}
+
+// WriteJSON writes the source map as JSON, where every ROM address has an entry [file, line, col]
+func (m *SourceMap) WriteJSON(w io.Writer) error {
+	js := jsonSourceMap{Version: sourceMapVersion, Files: m.Files, Map: make([][3]int, 0, len(m.Entries))}
+	if js.Files == nil {
+		js.Files = []string{}
+	}
+	for _, e := range m.Entries {
+		js.Map = append(js.Map, [3]int{e.File, e.Line, e.Col})
+	}
+	return json.NewEncoder(w).Encode(js)
+}
+
+// ReadSourceMap reads the source map written by WriteJSON
+func ReadSourceMap(r io.Reader) (*SourceMap, error) {
+	var js jsonSourceMap
+	if err := json.NewDecoder(r).Decode(&js); err != nil {
+		return nil, err
+	}
+	if js.Version != sourceMapVersion {
+		return nil, fmt.Errorf("Unsupported source map version %d", js.Version)
+	}
+
+	m := &SourceMap{Files: js.Files, Entries: make([]SourceMapEntry, 0, len(js.Map))}
+	for addr, e := range js.Map {
+		if e[0] < 0 || e[0] >= len(m.Files) {
+			return nil, fmt.Errorf("Source map entry %d has wrong file index %d", addr, e[0])
+		}
+		m.Entries = append(m.Entries, SourceMapEntry{File: e[0], Line: e[1], Col: e[2]})
+	}
+	return m, nil
+}
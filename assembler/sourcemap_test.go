@@ -0,0 +1,83 @@
+package assembler
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func TestSourceMap(t *testing.T) {
+	asm := "// Comment\n(START)\n  @START\n\n  0;JMP\n"
+	prog, err := Assemble(strings.NewReader(asm), Options{Name: "prog.asm"})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	// Source map must be the same after writing and reading
+	sb := strings.Builder{}
+	if err := prog.SourceMap().WriteJSON(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	m, err := ReadSourceMap(strings.NewReader(sb.String()))
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	wantLocs := []parser.Location{
+		{File: "prog.asm", Line: 3, Col: 3},
+		{File: "prog.asm", Line: 5, Col: 3},
+	}
+	for addr, want := range wantLocs {
+		loc, ok := m.Location(addr)
+		if !ok || loc != want {
+			t.Errorf("Location of %d: %+v; want %+v", addr, loc, want)
+		}
+		if actual, ok := m.Addr(want.File, want.Line); !ok || actual != addr {
+			t.Errorf("Address of %v: %v; want %v", want, actual, addr)
+		}
+	}
+
+	if loc, ok := m.Location(len(wantLocs)); ok {
+		t.Errorf("Location out of the program: %+v", loc)
+	}
+	if addr, ok := m.Addr("prog.asm", 1); ok {
+		t.Errorf("Address of the comment line: %v", addr)
+	}
+}
+
+func TestReadSourceMapError(t *testing.T) {
+	testCases := []string{
+		`{"version":2,"files":[],"map":[]}`,
+		`{"version":1,"files":["a.asm"],"map":[[1,1,1]]}`,
+		`{"version":1`,
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			if _, err := ReadSourceMap(strings.NewReader(tc)); err == nil {
+				t.Errorf("Error was not arisen as expected")
+			}
+		})
+	}
+}
+
+func TestSourceMapData(t *testing.T) {
+	prog, err := Assemble(strings.NewReader("@1\n.data D\n.word 5\n"), Options{Name: "prog.asm"})
+	if err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	m := prog.SourceMap()
+	if len(m.Files) != 1 {
+		t.Errorf("Files: %v; want only prog.asm", m.Files)
+	}
+	for addr := 0; addr < initInstrSize; addr++ {
+		if loc, _ := m.Location(addr); loc.Line != 3 {
+			t.Errorf("Line of %d: %v; want %v", addr, loc.Line, 3)
+		}
+	}
+}
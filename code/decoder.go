@@ -0,0 +1,60 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// Decoding tables are reversed encoding ones
+var (
+	destDecTable = reverseTable(destTable)
+	cmpDecTable  = reverseTable(cmpTable)
+	jmpDecTable  = reverseTable(jmpTable)
+)
+
+func reverseTable(tbl map[string]Word) map[Word]string {
+	rev := make(map[Word]string, len(tbl))
+	for k, v := range tbl {
+		rev[v] = k
+	}
+	return rev
+}
+
+// DecodeAInstr returns A-Instruction with a number from its machine word
+func DecodeAInstr(w Word) (parser.AInstruction, error) {
+	if !w.IsAInstr() {
+		return parser.AInstruction{}, &EncoderError{Msg: fmt.Sprintf("'%v' is not an A-Instruction", w)}
+	}
+	return parser.AInstruction{IsVar: false, Value: strconv.Itoa(w.Value())}, nil
+}
+
+// DecodeCInstr returns C-Instruction from its machine word. If any part of the word
+// does not match the language specification, the error will be returned
+func DecodeCInstr(w Word) (parser.CIntstruction, error) {
+	if !w.IsCInstr() {
+		return parser.CIntstruction{}, &EncoderError{Msg: fmt.Sprintf("'%v' has wrong C-Instruction prefix", w)}
+	}
+
+	var ci parser.CIntstruction
+	var err error
+	decode := func(tbl map[Word]string, val Word, dest *string) {
+		if err != nil {
+			return
+		}
+		if _, ok := tbl[val]; !ok {
+			err = &EncoderError{Msg: fmt.Sprintf("Cannot decode '%b' of '%v'", val, w)}
+			return
+		}
+		*dest = tbl[val]
+	}
+
+	decode(cmpDecTable, w.Comp(), &ci.Comp)
+	decode(destDecTable, w.Dest(), &ci.Dest)
+	decode(jmpDecTable, w.Jump(), &ci.Jump)
+	if err != nil {
+		return parser.CIntstruction{}, err
+	}
+	return ci, nil
+}
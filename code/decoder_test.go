@@ -0,0 +1,116 @@
+package code
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// word returns a word from binary digits separated by spaces
+func word(t *testing.T, s string) Word {
+	w, err := ParseWord(remSp(s))
+	if err != nil {
+		t.Fatalf("Cannot parse word: %v", err)
+	}
+	return w
+}
+
+func TestParseWordError(t *testing.T) {
+	testCases := []string{
+		remSp("1110 1111 1100 100"),   // short word
+		remSp("1110 1111 1100 1002"),  // not binary
+		remSp("1110 1111 1100 10001"), // long word
+		"",
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Word %v", tc), func(t *testing.T) {
+			actual, err := ParseWord(tc)
+			if err == nil {
+				t.Errorf("ParseWord did not returned an error: %v", actual)
+			}
+		})
+	}
+}
+
+func TestDecodeAInstr(t *testing.T) {
+	testCases := []struct {
+		word string
+		want parser.AInstruction
+	}{
+		{
+			word: ("0000 0000 0000 0000"),
+			want: parser.AInstruction{IsVar: false, Value: "0"},
+		},
+		{
+			word: ("0000 0000 0001 0000"),
+			want: parser.AInstruction{IsVar: false, Value: "16"},
+		},
+		{
+			word: ("0111 1111 1111 1111"),
+			want: parser.AInstruction{IsVar: false, Value: "32767"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.word, func(t *testing.T) {
+			actual, err := DecodeAInstr(word(t, tc.word))
+			if err != nil {
+				t.Errorf("DecodeAInstr returned unexpected error: %v", err)
+				return
+			}
+			if actual != tc.want {
+				t.Errorf("Actual: %+v, want: %+v", actual, tc.want)
+			}
+		})
+	}
+}
+
+// Every C-Instruction that can be encoded must be decoded back
+func TestDecodeCInstrRoundTrip(t *testing.T) {
+	for dest := range destTable {
+		for comp := range cmpTable {
+			for jump := range jmpTable {
+				ci := parser.CIntstruction{Dest: dest, Comp: comp, Jump: jump}
+				w, err := EncodeCInstr(ci)
+				if err != nil {
+					t.Errorf("EncodeCInstr returned unexpected error: %v", err)
+					return
+				}
+				actual, err := DecodeCInstr(w)
+				if err != nil {
+					t.Errorf("DecodeCInstr returned unexpected error: %v", err)
+					return
+				}
+				if actual != ci {
+					t.Errorf("Actual: %+v, want: %+v", actual, ci)
+					return
+				}
+			}
+		}
+	}
+}
+
+func TestDecodeCInstrError(t *testing.T) {
+	testCases := []string{
+		"0110 1111 1100 1000", // A-Instruction
+		"1000 1111 1100 1000", // wrong prefix
+		"1110 0000 0100 0000", // unknown comp
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Word %v", tc), func(t *testing.T) {
+			actual, err := DecodeCInstr(word(t, tc))
+			if err == nil {
+				t.Errorf("DecodeCInstr did not returned an error: %+v", actual)
+				return
+			}
+			de := &EncoderError{}
+			if !errors.As(err, &de) {
+				t.Errorf("DecodeCInstr retuned unexpected error type: %v", err)
+			}
+		})
+	}
+}
@@ -2,7 +2,6 @@ package code
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/verybigtuple/hackassembler/parser"
 )
@@ -10,76 +9,74 @@ import (
 const (
 	minInt = 0
 	maxInt = 32767
-
-	ainstrPrefix = "0"
-	cinstrPrefix = "111"
 )
 
-var destTable = map[string]string{
-	"":    "000",
-	"M":   "001",
-	"D":   "010",
-	"MD":  "011",
-	"A":   "100",
-	"AM":  "101",
-	"AD":  "110",
-	"AMD": "111",
+var destTable = map[string]Word{
+	"":    0b000,
+	"M":   0b001,
+	"D":   0b010,
+	"MD":  0b011,
+	"A":   0b100,
+	"AM":  0b101,
+	"AD":  0b110,
+	"AMD": 0b111,
 }
 
-var jmpTable = map[string]string{
-	"":    "000",
-	"JGT": "001",
-	"JEQ": "010",
-	"JGE": "011",
-	"JLT": "100",
-	"JNE": "101",
-	"JLE": "110",
-	"JMP": "111",
+var jmpTable = map[string]Word{
+	"":    0b000,
+	"JGT": 0b001,
+	"JEQ": 0b010,
+	"JGE": 0b011,
+	"JLT": 0b100,
+	"JNE": 0b101,
+	"JLE": 0b110,
+	"JMP": 0b111,
 }
 
-var cmpTable = map[string]string{
-	"0":   "0" + "101" + "010",
-	"1":   "0" + "111" + "111",
-	"-1":  "0" + "111" + "010",
-	"D":   "0" + "001" + "100",
-	"A":   "0" + "110" + "000",
-	"M":   "1" + "110" + "000",
-	"!D":  "0" + "001" + "101",
-	"!A":  "0" + "110" + "001",
-	"!M":  "1" + "110" + "001",
-	"-D":  "0" + "001" + "111",
-	"-A":  "0" + "110" + "011",
-	"-M":  "1" + "110" + "011",
-	"D+1": "0" + "011" + "111",
-	"A+1": "0" + "110" + "111",
-	"M+1": "1" + "110" + "111",
-	"D-1": "0" + "001" + "110",
-	"A-1": "0" + "110" + "010",
-	"M-1": "1" + "110" + "010",
-	"D+A": "0" + "000" + "010",
-	"D+M": "1" + "000" + "010",
-	"D-A": "0" + "010" + "011",
-	"D-M": "1" + "010" + "011",
-	"A-D": "0" + "000" + "111",
-	"M-D": "1" + "000" + "111",
-	"D&A": "0" + "000" + "000",
-	"D&M": "1" + "000" + "000",
-	"D|A": "0" + "010" + "101",
-	"D|M": "1" + "010" + "101",
+// Computation bits are "a c1 c2 c3 c4 c5 c6"
+var cmpTable = map[string]Word{
+	"0":   0b0_101_010,
+	"1":   0b0_111_111,
+	"-1":  0b0_111_010,
+	"D":   0b0_001_100,
+	"A":   0b0_110_000,
+	"M":   0b1_110_000,
+	"!D":  0b0_001_101,
+	"!A":  0b0_110_001,
+	"!M":  0b1_110_001,
+	"-D":  0b0_001_111,
+	"-A":  0b0_110_011,
+	"-M":  0b1_110_011,
+	"D+1": 0b0_011_111,
+	"A+1": 0b0_110_111,
+	"M+1": 0b1_110_111,
+	"D-1": 0b0_001_110,
+	"A-1": 0b0_110_010,
+	"M-1": 0b1_110_010,
+	"D+A": 0b0_000_010,
+	"D+M": 0b1_000_010,
+	"D-A": 0b0_010_011,
+	"D-M": 0b1_010_011,
+	"A-D": 0b0_000_111,
+	"M-D": 0b1_000_111,
+	"D&A": 0b0_000_000,
+	"D&M": 0b1_000_000,
+	"D|A": 0b0_010_101,
+	"D|M": 0b1_010_101,
 }
 
-// encodeNumber returns 15 bit as string. If argument is less than zero
+// encodeNumber returns 15 bit of A-Instruction. If argument is less than zero
 // or more than 2^15-1, it returns error
-func encodeNumber(n int) (string, error) {
+func encodeNumber(n int) (Word, error) {
 	if n < minInt || n > maxInt {
-		return "", &EncoderError{Msg: fmt.Sprintf("Cannot decode %v as it is out of bound", n)}
+		return 0, &EncoderError{Msg: fmt.Sprintf("Cannot decode %v as it is out of bound", n)}
 	}
-	return fmt.Sprintf("%015b", n), nil
+	return Word(n), nil
 }
 
-// EncodeAInstr returns string binary (as specified) of A-Instruction. If A-Intruction is a new var,
+// EncodeAInstr returns machine word (as specified) of A-Instruction. If A-Intruction is a new var,
 //  it will be added to SymbolTable
-func EncodeAInstr(ai parser.AInstruction, st *SymbolTable) (string, error) {
+func EncodeAInstr(ai parser.AInstruction, st *SymbolTable) (Word, error) {
 	var n int
 	var err error
 
@@ -92,6 +89,10 @@ func EncodeAInstr(ai parser.AInstruction, st *SymbolTable) (string, error) {
 		n = addr
 	}
 
+	if ai.IsExpr {
+		return encodeExpr(ai.Value, st)
+	}
+
 	if ai.IsVar {
 		if !st.Exists(ai.Value) {
 			getAddr(st.AddVar)
@@ -99,27 +100,43 @@ func EncodeAInstr(ai parser.AInstruction, st *SymbolTable) (string, error) {
 			getAddr(st.Get)
 		}
 	} else {
-		getAddr(strconv.Atoi)
+		getAddr(parser.ParseNumber)
+		if err != nil {
+			err = &EncoderError{Msg: fmt.Sprintf("Cannot encode %v: %v", ai.Value, err)}
+		}
 	}
 
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	sn, err := encodeNumber(n)
+	return encodeNumber(n)
+}
+
+// encodeExpr returns 15 bit of A-Instruction with a constant expression. All symbols
+// of the expression must be defined, so labels are known only after the label pass
+func encodeExpr(s string, st *SymbolTable) (Word, error) {
+	expr, err := parser.ParseExpr(s)
+	if err != nil {
+		return 0, err
+	}
+	n, err := expr.Eval(st.Get)
 	if err != nil {
-		return "", err
+		return 0, &EncoderError{Msg: fmt.Sprintf("Cannot evaluate %v: %v", s, err)}
+	}
+	if n < minInt || n > maxInt {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Value %v of %v is out of bound %v..%v", n, s, minInt, maxInt)}
 	}
-	return ainstrPrefix + sn, nil
+	return Word(n), nil
 }
 
-// EncodeCInstr returns string binary (as specified) of C-Instruction according
+// EncodeCInstr returns machine word (as specified) of C-Instruction according
 // to the language specification.
-func EncodeCInstr(ci parser.CIntstruction) (string, error) {
+func EncodeCInstr(ci parser.CIntstruction) (Word, error) {
 	var err error
-	var encDest, encComp, encJmp string
+	var encDest, encComp, encJmp Word
 
-	encode := func(tbl map[string]string, val string, dest *string) {
+	encode := func(tbl map[string]Word, val string, dest *Word) {
 		if err != nil {
 			return
 		}
@@ -134,8 +151,8 @@ func EncodeCInstr(ci parser.CIntstruction) (string, error) {
 	encode(cmpTable, ci.Comp, &encComp)
 	encode(jmpTable, ci.Jump, &encJmp)
 	if err != nil {
-		return "", err
+		return 0, err
 	}
 
-	return cinstrPrefix + encComp + encDest + encJmp, nil
+	return cinstrBits | encComp<<compShift | encDest<<destShift | encJmp, nil
 }
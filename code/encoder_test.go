@@ -10,14 +10,14 @@ import (
 )
 
 func TestCmpTableUnique(t *testing.T) {
-	m := map[string]int{}
+	m := map[Word]int{}
 	for k, v := range cmpTable {
 		if _, ok := m[v]; !ok {
 			m[v] = 0
 		}
 		m[v]++
 		if m[v] > 1 {
-			t.Errorf("Value '%s' is not unique for key '%s'", v, k)
+			t.Errorf("Value '%v' is not unique for key '%s'", v, k)
 			return
 		}
 	}
@@ -27,13 +27,13 @@ func TestCmpTableAMBit(t *testing.T) {
 	for k, v := range cmpTable {
 		switch {
 		case strings.Contains(k, "A"):
-			if v[0] != '0' {
-				t.Errorf("Value '%s' for key '%s' has wrong first bit", v, k)
+			if v>>6 != 0 {
+				t.Errorf("Value '%v' for key '%s' has wrong first bit", v, k)
 				return
 			}
 		case strings.Contains(k, "M"):
-			if v[0] != '1' {
-				t.Errorf("Value '%s' for key '%s' has wrong first bit", v, k)
+			if v>>6 != 1 {
+				t.Errorf("Value '%v' for key '%s' has wrong first bit", v, k)
 				return
 			}
 		}
@@ -78,7 +78,7 @@ func TestDecodeNumberRegular(t *testing.T) {
 				t.Errorf("EncodeNumber returned unexpected error: %v", err)
 				return
 			}
-			if actual != tc.want {
+			if fmt.Sprintf("%015b", actual) != tc.want {
 				t.Errorf("Actual: %v, want: %v", actual, tc.want)
 			}
 		})
@@ -135,6 +135,26 @@ func TestEncodeAInstr(t *testing.T) {
 			instr: parser.AInstruction{IsVar: true, Value: "i2"},
 			want:  remSp("0000 0000 0001 0001"),
 		},
+		{
+			instr: parser.AInstruction{IsVar: false, Value: "0x7FFF"},
+			want:  remSp("0111 1111 1111 1111"),
+		},
+		{
+			instr: parser.AInstruction{IsVar: false, Value: "0b101"},
+			want:  remSp("0000 0000 0000 0101"),
+		},
+		{
+			instr: parser.AInstruction{IsVar: false, Value: "'A'"},
+			want:  remSp("0000 0000 0100 0001"),
+		},
+		{
+			instr: parser.AInstruction{IsExpr: true, Value: "SCREEN+32"},
+			want:  remSp("0100 0000 0010 0000"),
+		},
+		{
+			instr: parser.AInstruction{IsExpr: true, Value: "(R15+1)*2"},
+			want:  remSp("0000 0000 0010 0000"),
+		},
 		// Repeat the same instruction on purpose
 		{
 			instr: parser.AInstruction{IsVar: true, Value: "i2"},
@@ -149,7 +169,7 @@ func TestEncodeAInstr(t *testing.T) {
 				t.Errorf("DecodeAInstr returned unexpected error: %v", err)
 				return
 			}
-			if actual != tc.want {
+			if actual.String() != tc.want {
 				t.Errorf("Actual: %v, want: %v", actual, tc.want)
 			}
 		})
@@ -160,8 +180,15 @@ func TestEncodeAInstrError(t *testing.T) {
 	symTable := NewSymbolTable()
 
 	testCases := []parser.AInstruction{
-		{IsVar: false, Value: "i1"},    // not digit value
-		{IsVar: false, Value: "65536"}, // value is very big
+		{IsVar: false, Value: "i1"},     // not digit value
+		{IsVar: false, Value: "65536"},  // value is very big
+		{IsVar: false, Value: "0x8000"}, // value is bigger than maxInt
+		{IsVar: false, Value: "0xFFFFFFFFFF"},
+		{IsExpr: true, Value: "SCREEN*2"}, // value is bigger than maxInt
+		{IsExpr: true, Value: "1-2"},      // negative value
+		{IsExpr: true, Value: "undefined+1"},
+		{IsExpr: true, Value: "65536*65536*65536*65536"}, // wraps to 0
+		{IsExpr: true, Value: "(1<<62)*4+5"},             // wraps to 5
 	}
 
 	for _, tc := range testCases {
@@ -204,7 +231,7 @@ func TestEncodeCInstr(t *testing.T) {
 				t.Errorf("EncodeCInstr returned unexpected error: %v", err)
 				return
 			}
-			if actual != tc.want {
+			if actual.String() != tc.want {
 				t.Errorf("Actual: %v, want: %v", actual, tc.want)
 			}
 		})
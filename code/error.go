@@ -1,8 +1,19 @@
 package code
 
-//EncoderError is error returned by any decoder function
+import (
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+//EncoderError is error returned by any decoder function. Loc is set only when
+// the encoded instruction is known to be a line of a source file
 type EncoderError struct {
 	Msg string
+	Loc parser.Location
 }
 
-func (e *EncoderError) Error() string { return e.Msg }
+func (e *EncoderError) Error() string {
+	if e.Loc.Line == 0 {
+		return e.Msg
+	}
+	return e.Loc.String() + ": " + e.Msg + e.Loc.Trace()
+}
@@ -0,0 +1,51 @@
+package code
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Headers of sections in *.sym files
+var symSections = map[SymbolKind]string{
+	PredefinedSymbol: "; Predefined symbols (RAM)",
+	LabelSymbol:      "; Labels (ROM)",
+	VarSymbol:        "; Variables (RAM)",
+	ConstSymbol:      "; Constants",
+	ReservedSymbol:   "; Reserved RAM",
+}
+
+type jsonSymbol struct {
+	Name    string `json:"name"`
+	Address int    `json:"address"`
+	Kind    string `json:"kind"`
+}
+
+// WriteJSON writes all symbols as a JSON array of objects with name, address and kind
+func (t *SymbolTable) WriteJSON(w io.Writer) error {
+	symbols := t.Symbols()
+	js := make([]jsonSymbol, 0, len(symbols))
+	for _, s := range symbols {
+		js = append(js, jsonSymbol{Name: s.Name, Address: s.Addr, Kind: s.Kind.String()})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(js)
+}
+
+// WriteSym writes all symbols as a plain text: one symbol per line as the hex address
+// and the name. Symbols are grouped by kind in sections started with a comment line
+func (t *SymbolTable) WriteSym(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	kind := SymbolKind(-1)
+	for _, s := range t.Symbols() {
+		if s.Kind != kind {
+			kind = s.Kind
+			fmt.Fprintln(bw, symSections[kind])
+		}
+		fmt.Fprintf(bw, "%04X %s\n", s.Addr, s.Name)
+	}
+	return bw.Flush()
+}
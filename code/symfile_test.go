@@ -0,0 +1,81 @@
+package code
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func newTestTable(t *testing.T) *SymbolTable {
+	st := NewSymbolTable()
+	if _, err := st.AddLabel("LOOP", 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.AddConst("WIDTH", 32); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.AddVar("i"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.AddVarAt("buf", 100, 4); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestSymbolKinds(t *testing.T) {
+	st := newTestTable(t)
+	want := map[string]SymbolKind{"SP": PredefinedSymbol, "KBD": PredefinedSymbol, "LOOP": LabelSymbol, "i": VarSymbol, "WIDTH": ConstSymbol, "buf": ReservedSymbol}
+	for name, kind := range want {
+		if actual := st.Kinds[name]; actual != kind {
+			t.Errorf("Kind of %s: %v; want %v", name, actual, kind)
+		}
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	st := newTestTable(t)
+	sb := strings.Builder{}
+	if err := st.WriteJSON(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	var actual []jsonSymbol
+	if err := json.Unmarshal([]byte(sb.String()), &actual); err != nil {
+		t.Errorf("Cannot unmarshal: %v", err)
+		return
+	}
+	if len(actual) != len(st.Table) {
+		t.Errorf("Actual len: %v; want len: %v", len(actual), len(st.Table))
+		return
+	}
+	wantTail := []jsonSymbol{
+		{Name: "LOOP", Address: 10, Kind: "label"},
+		{Name: "i", Address: 16, Kind: "variable"},
+		{Name: "WIDTH", Address: 32, Kind: "constant"},
+		{Name: "buf", Address: 100, Kind: "reserved"},
+	}
+	for i, s := range actual[len(actual)-len(wantTail):] {
+		if s != wantTail[i] {
+			t.Errorf("Actual: %+v; want %+v", s, wantTail[i])
+		}
+	}
+}
+
+func TestWriteSym(t *testing.T) {
+	st := newTestTable(t)
+	sb := strings.Builder{}
+	if err := st.WriteSym(&sb); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	want := "; Labels (ROM)\n000A LOOP\n; Variables (RAM)\n0010 i\n; Constants\n0020 WIDTH\n; Reserved RAM\n0064 buf\n"
+	if actual := sb.String(); !strings.HasSuffix(actual, want) {
+		t.Errorf("Actual:\n%s\nwant suffix:\n%s", actual, want)
+	}
+	if !strings.HasPrefix(sb.String(), "; Predefined symbols (RAM)\n0000 R0\n0000 SP\n0001 LCL") {
+		t.Errorf("Actual:\n%s\ndoes not start with predefined symbols", sb.String())
+	}
+}
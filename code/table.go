@@ -2,8 +2,18 @@ package code
 
 import (
 	"fmt"
+	"sort"
 )
 
+// Memory maps of inputs and outputs according to the language specification
+const (
+	ScreenAddr = 16384
+	KbdAddr    = 24576
+)
+
+// ROMSize is the number of words in ROM
+const ROMSize = maxROM - minROM + 1
+
 const (
 	minUserRAM = 16
 	maxUserRAM = 16383
@@ -26,25 +36,68 @@ var initTable = map[string]int{
 	"R10": 10, "R11": 11, "R12": 12, "R13": 13, "R14": 14, "R15": 15,
 
 	// Inputs
-	"SCREEN": 16384,
-	"KBD":    24576,
+	"SCREEN": ScreenAddr,
+	"KBD":    KbdAddr,
+}
+
+// SymbolKind shows how a symbol got into the SymbolTable
+type SymbolKind int
+
+// Kinds of symbols
+const (
+	PredefinedSymbol SymbolKind = iota
+	LabelSymbol
+	VarSymbol
+	ConstSymbol
+	// ReservedSymbol is a part of RAM reserved by a directive, i.e. an array or data
+	ReservedSymbol
+)
+
+var symbolKindNames = map[SymbolKind]string{
+	PredefinedSymbol: "predefined",
+	LabelSymbol:      "label",
+	VarSymbol:        "variable",
+	ConstSymbol:      "constant",
+	ReservedSymbol:   "reserved",
+}
+
+func (k SymbolKind) String() string {
+	return symbolKindNames[k]
+}
+
+// Symbol is an entry of the SymbolTable
+type Symbol struct {
+	Name string
+	Addr int
+	Kind SymbolKind
 }
 
-// SymbolTable is register for ROM labels and RAM variables
+// SymbolTable is register for ROM labels, RAM variables and constants
 type SymbolTable struct {
 	Table        map[string]int
+	Kinds        map[string]SymbolKind
 	UserRegister int
+	// ranges are parts of user RAM taken by variables
+	ranges []ramRange
+}
+
+// ramRange is a part of RAM from..to-1 taken by the variable name
+type ramRange struct {
+	name     string
+	from, to int
 }
 
 // NewSymbolTable creates a new SymbolTable and init it with predefined vars
 func NewSymbolTable() *SymbolTable {
 	// Copying initTable
 	newTable := make(map[string]int, len(initTable))
+	kinds := make(map[string]SymbolKind, len(initTable))
 	for k, v := range initTable {
 		newTable[k] = v
+		kinds[k] = PredefinedSymbol
 	}
 
-	vt := SymbolTable{Table: newTable, UserRegister: minUserRAM}
+	vt := SymbolTable{Table: newTable, Kinds: kinds, UserRegister: minUserRAM}
 	return &vt
 }
 
@@ -57,15 +110,84 @@ func (t *SymbolTable) Exists(name string) bool {
 // AddVar adds a new user var. The address if the var is added automatically and is returned
 // from the function. If the Var already exists, the error will be returned
 func (t *SymbolTable) AddVar(name string) (int, error) {
-	if t.UserRegister > maxUserRAM {
-		return 0, &EncoderError{Msg: fmt.Sprintf("User RAM ran out. Address %v is reserved", t.UserRegister)}
+	addr := t.nextFree(1)
+	if addr > maxUserRAM {
+		return 0, &EncoderError{Msg: fmt.Sprintf("User RAM ran out. Address %v is reserved", addr)}
 	}
 	if t.Exists(name) {
 		return 0, &EncoderError{Msg: fmt.Sprintf("Variable '%v' already exists", name)}
 	}
-	t.Table[name] = t.UserRegister
-	t.UserRegister++
-	return t.Table[name], nil
+	t.addRange(name, addr, 1, VarSymbol)
+	t.UserRegister = addr + 1
+	return addr, nil
+}
+
+// AddData adds a reserved symbol that takes size words of RAM, i.e. an array, a table or a string.
+// It is allocated from the same pool as variables of AddVar and the address is returned
+func (t *SymbolTable) AddData(name string, size int) (int, error) {
+	addr := t.nextFree(size)
+	if addr+size-1 > maxUserRAM {
+		return 0, &EncoderError{Msg: fmt.Sprintf("User RAM ran out. %v words of '%v' do not fit", size, name)}
+	}
+	if t.Exists(name) {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Variable '%v' already exists", name)}
+	}
+	t.addRange(name, addr, size, ReservedSymbol)
+	t.UserRegister = addr + size
+	return addr, nil
+}
+
+// AddVarAt adds a reserved symbol that takes size words of RAM from the address addr.
+// The words cannot be used by other variables, so they are skipped by AddVar and AddData.
+// Registers R0-R15, the screen and the keyboard cannot be taken
+func (t *SymbolTable) AddVarAt(name string, addr, size int) (int, error) {
+	if t.Exists(name) {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Variable '%v' already exists", name)}
+	}
+	if size < 1 {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Size %v of '%v' must be positive", size, name)}
+	}
+	if addr < minUserRAM {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Variable '%v' at address %v overlaps registers R0-R15", name, addr)}
+	}
+	if addr+size-1 > maxUserRAM {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Variable '%v' at %v..%v overlaps the screen or the keyboard", name, addr, addr+size-1)}
+	}
+	if r, ok := t.overlap(addr, addr+size); ok {
+		return 0, &EncoderError{
+			Msg: fmt.Sprintf("Variable '%v' at %v..%v overlaps '%v' at %v..%v", name, addr, addr+size-1, r.name, r.from, r.to-1),
+		}
+	}
+	t.addRange(name, addr, size, ReservedSymbol)
+	return addr, nil
+}
+
+func (t *SymbolTable) addRange(name string, addr, size int, kind SymbolKind) {
+	t.Table[name] = addr
+	t.Kinds[name] = kind
+	t.ranges = append(t.ranges, ramRange{name: name, from: addr, to: addr + size})
+}
+
+// overlap returns a taken range of RAM that overlaps from..to-1
+func (t *SymbolTable) overlap(from, to int) (ramRange, bool) {
+	for _, r := range t.ranges {
+		if r.from < to && from < r.to {
+			return r, true
+		}
+	}
+	return ramRange{}, false
+}
+
+// nextFree returns the first address from UserRegister where size words are not taken
+func (t *SymbolTable) nextFree(size int) int {
+	addr := t.UserRegister
+	for {
+		r, ok := t.overlap(addr, addr+size)
+		if !ok {
+			return addr
+		}
+		addr = r.to
+	}
 }
 
 // Get returns RAM address for a var and ROM address for a label. If var or label
@@ -88,5 +210,45 @@ func (t *SymbolTable) AddLabel(name string, val int) (int, error) {
 	}
 
 	t.Table[name] = val
+	t.Kinds[name] = LabelSymbol
+	return val, nil
+}
+
+// AddConst adds a named constant. Constants do not take RAM, so they are not counted
+// in UserRegister. A constant cannot be redefined
+func (t *SymbolTable) AddConst(name string, val int) (int, error) {
+	if t.Kinds[name] == ConstSymbol && t.Exists(name) {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Constant '%v' is already defined", name)}
+	}
+	if t.Exists(name) {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Cannot define constant '%v' as %v with the same name exists", name, t.Kinds[name])}
+	}
+
+	if val < minInt || val > maxInt {
+		return 0, &EncoderError{Msg: fmt.Sprintf("Constant '%v' has value %v out of bound", name, val)}
+	}
+
+	t.Table[name] = val
+	t.Kinds[name] = ConstSymbol
 	return val, nil
 }
+
+// Symbols returns all symbols sorted by kind, address and name
+func (t *SymbolTable) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(t.Table))
+	for name, addr := range t.Table {
+		symbols = append(symbols, Symbol{Name: name, Addr: addr, Kind: t.Kinds[name]})
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		a, b := symbols[i], symbols[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Addr != b.Addr {
+			return a.Addr < b.Addr
+		}
+		return a.Name < b.Name
+	})
+	return symbols
+}
@@ -0,0 +1,78 @@
+package code
+
+import "testing"
+
+func TestAddConstError(t *testing.T) {
+	st := newTestTable(t)
+	testCases := []struct {
+		name string
+		val  int
+	}{
+		{name: "WIDTH", val: 32}, // redefinition
+		{name: "LOOP", val: 1},   // label exists
+		{name: "SCREEN", val: 1}, // predefined symbol
+		{name: "BIG", val: 32768},
+		{name: "NEG", val: -1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := st.AddConst(tc.name, tc.val); err == nil {
+				t.Errorf("AddConst did not returned an error")
+			}
+		})
+	}
+}
+
+func TestAddData(t *testing.T) {
+	st := NewSymbolTable()
+	table, err := st.AddData("TABLE", 10)
+	if err != nil {
+		t.Fatalf("AddData returned an error: %v", err)
+	}
+	i, _ := st.AddVar("i")
+	if table != minUserRAM || i != minUserRAM+10 {
+		t.Errorf("Addresses: TABLE %v, i %v; want %v, %v", table, i, minUserRAM, minUserRAM+10)
+	}
+
+	if _, err := st.AddData("i", 1); err == nil {
+		t.Errorf("AddData did not return an error for an existing name")
+	}
+	if _, err := st.AddData("BIG", maxUserRAM); err == nil {
+		t.Errorf("AddData did not return an error for too big data")
+	}
+}
+
+func TestAddVarAt(t *testing.T) {
+	st := NewSymbolTable()
+	if _, err := st.AddVarAt("BUF", 17, 2); err != nil {
+		t.Fatalf("AddVarAt returned an error: %v", err)
+	}
+	// Auto variables skip the reserved words 17 and 18
+	want := map[string]int{"a": 16, "b": 19}
+	for _, name := range []string{"a", "b"} {
+		if addr, _ := st.AddVar(name); addr != want[name] {
+			t.Errorf("Variable %v: actual %v; want %v", name, addr, want[name])
+		}
+	}
+
+	testCases := []struct {
+		name       string
+		addr, size int
+	}{
+		{name: "a", addr: 100, size: 1},   // exists
+		{name: "R", addr: 15, size: 1},    // registers
+		{name: "S", addr: 16383, size: 2}, // screen
+		{name: "X", addr: 18, size: 1},    // reserved
+		{name: "Y", addr: 10000, size: 0}, // size
+		{name: "Z", addr: 19, size: 1},    // auto variable
+		{name: "W", addr: ScreenAddr, size: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := st.AddVarAt(tc.name, tc.addr, tc.size); err == nil {
+				t.Errorf("AddVarAt did not return an error")
+			}
+		})
+	}
+}
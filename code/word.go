@@ -0,0 +1,69 @@
+package code
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// WordSize is the number of bits in a machine word
+const WordSize = 16
+
+// Word is a 16-bit machine word of Hack computer: an encoded instruction or data.
+// A-Instruction is 0vvv vvvv vvvv vvvv, C-Instruction is 111a cccc ccdd djjj
+type Word uint16
+
+// Bit fields of instructions
+const (
+	cinstrBits Word = 0b111 << 13
+	aValueMask Word = 0x7FFF
+
+	compShift = 6
+	compMask  = 0b1111111
+	destShift = 3
+	destMask  = 0b111
+	jumpMask  = 0b111
+)
+
+// IsAInstr returns true if the word is an A-Instruction
+func (w Word) IsAInstr() bool {
+	return w>>15 == 0
+}
+
+// IsCInstr returns true if the word has the prefix of C-Instruction
+func (w Word) IsCInstr() bool {
+	return w&cinstrBits == cinstrBits
+}
+
+// Value returns 15-bit value of A-Instruction
+func (w Word) Value() int {
+	return int(w & aValueMask)
+}
+
+// Comp returns 7 bits "a c1 c2 c3 c4 c5 c6" of C-Instruction
+func (w Word) Comp() Word {
+	return w >> compShift & compMask
+}
+
+// Dest returns 3 bits "d1 d2 d3" (A, D, M) of C-Instruction
+func (w Word) Dest() Word {
+	return w >> destShift & destMask
+}
+
+// Jump returns 3 bits "j1 j2 j3" (<0, =0, >0) of C-Instruction
+func (w Word) Jump() Word {
+	return w & jumpMask
+}
+
+// String returns the word as 16 binary digits as it is written in *.hack files
+func (w Word) String() string {
+	return fmt.Sprintf("%016b", uint16(w))
+}
+
+// ParseWord returns a word written as 16 binary digits as in *.hack files
+func ParseWord(s string) (Word, error) {
+	n, err := strconv.ParseUint(s, 2, WordSize)
+	if err != nil || len(s) != WordSize {
+		return 0, &EncoderError{Msg: fmt.Sprintf("'%v' is not a %d-bit binary word", s, WordSize)}
+	}
+	return Word(n), nil
+}
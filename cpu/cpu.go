@@ -0,0 +1,217 @@
+// Package cpu emulates the Hack computer: CPU with A, D and PC registers, ROM and RAM
+package cpu
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+const (
+	// ROMSize is the number of words in ROM
+	ROMSize = code.ROMSize
+	// RAMSize is the number of words in RAM
+	RAMSize = 32768
+	// ScreenSize is the number of words mapped to the screen
+	ScreenSize = code.KbdAddr - code.ScreenAddr
+
+	addrMask = 0x7FFF
+)
+
+// Bits of computation field "a c1 c2 c3 c4 c5 c6" of C-Instruction
+const (
+	aBit  = 1 << 6
+	zxBit = 1 << 5
+	nxBit = 1 << 4
+	zyBit = 1 << 3
+	nyBit = 1 << 2
+	fBit  = 1 << 1
+	noBit = 1 << 0
+)
+
+// Bits of destination and jump fields of C-Instruction
+const (
+	destABit = 1 << 2
+	destDBit = 1 << 1
+	destMBit = 1 << 0
+
+	jltBit = 1 << 2
+	jeqBit = 1 << 1
+	jgtBit = 1 << 0
+)
+
+// CPU is the Hack computer. Registers and memory words are 16-bit
+type CPU struct {
+	A   uint16
+	D   uint16
+	PC  uint16
+	ROM [ROMSize]code.Word
+	RAM [RAMSize]uint16
+	// Time is the number of executed cycles since the last reset
+	Time int
+}
+
+// New returns a pointer to a new CPU with empty ROM and RAM
+func New() *CPU {
+	return &CPU{}
+}
+
+// Load loads machine words (as they are produced by the encoder) into ROM
+// starting from the address 0. The rest of ROM is cleared
+func (c *CPU) Load(words []code.Word) error {
+	if len(words) > ROMSize {
+		return fmt.Errorf("Program has %d words, but ROM size is %d", len(words), ROMSize)
+	}
+
+	c.ROM = [ROMSize]code.Word{}
+	copy(c.ROM[:], words)
+	return nil
+}
+
+// LoadHack loads a program from a *.hack file: one binary word per line
+func (c *CPU) LoadHack(r io.Reader) error {
+	words, err := readHack(r)
+	if err != nil {
+		return err
+	}
+	return c.Load(words)
+}
+
+// LoadRAM copies words to RAM starting from the address 0. The rest of RAM is not changed
+func (c *CPU) LoadRAM(words []code.Word) error {
+	if len(words) > RAMSize {
+		return fmt.Errorf("RAM image has %d words, but RAM size is %d", len(words), RAMSize)
+	}
+	for i, w := range words {
+		c.RAM[i] = uint16(w)
+	}
+	return nil
+}
+
+// LoadRAMHack loads a RAM image from a file in *.hack format
+func (c *CPU) LoadRAMHack(r io.Reader) error {
+	words, err := readHack(r)
+	if err != nil {
+		return err
+	}
+	return c.LoadRAM(words)
+}
+
+// readHack reads words of a *.hack file
+func readHack(r io.Reader) ([]code.Word, error) {
+	var words []code.Word
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			continue
+		}
+		w, err := code.ParseWord(s)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+		}
+		words = append(words, w)
+	}
+	return words, scanner.Err()
+}
+
+// Reset sets PC and Time to 0. Registers and memory are not changed as it is in the hardware
+func (c *CPU) Reset() {
+	c.PC = 0
+	c.Time = 0
+}
+
+// SetKey sets the code of a pressed key. Zero means no key is pressed
+func (c *CPU) SetKey(key uint16) {
+	c.RAM[code.KbdAddr] = key
+}
+
+// Screen returns the part of RAM that is mapped to the screen
+func (c *CPU) Screen() []uint16 {
+	return c.RAM[code.ScreenAddr:code.KbdAddr]
+}
+
+// Step executes one instruction from ROM at PC
+func (c *CPU) Step() {
+	instr := c.ROM[c.PC&addrMask]
+	c.Time++
+
+	if instr.IsAInstr() {
+		c.A = uint16(instr)
+		c.PC++
+		return
+	}
+
+	comp := instr.Comp()
+	addr := c.A & addrMask
+	y := c.A
+	if comp&aBit != 0 {
+		y = c.RAM[addr]
+	}
+	out := alu(c.D, y, comp)
+
+	jump := false
+	switch jmp := instr.Jump(); {
+	case int16(out) < 0:
+		jump = jmp&jltBit != 0
+	case out == 0:
+		jump = jmp&jeqBit != 0
+	default:
+		jump = jmp&jgtBit != 0
+	}
+	if jump {
+		c.PC = c.A
+	} else {
+		c.PC++
+	}
+
+	// All destinations use the value of A before the instruction
+	dest := instr.Dest()
+	if dest&destMBit != 0 {
+		c.RAM[addr] = out
+	}
+	if dest&destABit != 0 {
+		c.A = out
+	}
+	if dest&destDBit != 0 {
+		c.D = out
+	}
+}
+
+// Run executes n cycles
+func (c *CPU) Run(n int) {
+	for i := 0; i < n; i++ {
+		c.Step()
+	}
+}
+
+// alu computes the output of ALU according to control bits of the computation field
+func alu(x, y uint16, comp code.Word) uint16 {
+	if comp&zxBit != 0 {
+		x = 0
+	}
+	if comp&nxBit != 0 {
+		x = ^x
+	}
+	if comp&zyBit != 0 {
+		y = 0
+	}
+	if comp&nyBit != 0 {
+		y = ^y
+	}
+
+	var out uint16
+	if comp&fBit != 0 {
+		out = x + y
+	} else {
+		out = x & y
+	}
+
+	if comp&noBit != 0 {
+		out = ^out
+	}
+	return out
+}
@@ -0,0 +1,142 @@
+package cpu
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/assembler"
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+func loadAsm(t *testing.T, asm string) *CPU {
+	prog, err := assembler.Assemble(strings.NewReader(asm), assembler.Options{})
+	if err != nil {
+		t.Fatalf("Cannot assemble: %v", err)
+	}
+	c := New()
+	if err := c.Load(prog.Words); err != nil {
+		t.Fatalf("Cannot load: %v", err)
+	}
+	return c
+}
+
+func TestALU(t *testing.T) {
+	const d, a, m = 5, 3, 7
+
+	testCases := map[string]int16{
+		"0": 0, "1": 1, "-1": -1,
+		"D": d, "A": a, "M": m,
+		"!D": ^d, "!A": ^a, "!M": ^m,
+		"-D": -d, "-A": -a, "-M": -m,
+		"D+1": d + 1, "A+1": a + 1, "M+1": m + 1,
+		"D-1": d - 1, "A-1": a - 1, "M-1": m - 1,
+		"D+A": d + a, "D+M": d + m,
+		"D-A": d - a, "D-M": d - m,
+		"A-D": a - d, "M-D": m - d,
+		"D&A": d & a, "D&M": d & m,
+		"D|A": d | a, "D|M": d | m,
+	}
+
+	for comp, want := range testCases {
+		t.Run(comp, func(t *testing.T) {
+			w, err := code.EncodeCInstr(parser.CIntstruction{Dest: "D", Comp: comp})
+			if err != nil {
+				t.Errorf("Cannot encode: %v", err)
+				return
+			}
+			c := New()
+			if err := c.Load([]code.Word{w}); err != nil {
+				t.Errorf("Cannot load: %v", err)
+				return
+			}
+			c.D, c.A, c.RAM[a] = d, a, m
+			c.Step()
+			if int16(c.D) != want {
+				t.Errorf("Actual: %v, want: %v", int16(c.D), want)
+			}
+		})
+	}
+}
+
+func TestJump(t *testing.T) {
+	testCases := []struct {
+		jump string
+		d    int16
+		want bool
+	}{
+		{jump: "JGT", d: 1, want: true},
+		{jump: "JGT", d: 0, want: false},
+		{jump: "JEQ", d: 0, want: true},
+		{jump: "JEQ", d: -1, want: false},
+		{jump: "JGE", d: 0, want: true},
+		{jump: "JGE", d: -1, want: false},
+		{jump: "JLT", d: -1, want: true},
+		{jump: "JLT", d: 1, want: false},
+		{jump: "JNE", d: 1, want: true},
+		{jump: "JNE", d: 0, want: false},
+		{jump: "JLE", d: -1, want: true},
+		{jump: "JLE", d: 1, want: false},
+		{jump: "JMP", d: 1, want: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s %d", tc.jump, tc.d), func(t *testing.T) {
+			c := loadAsm(t, "@100\nD;"+tc.jump)
+			c.D = uint16(tc.d)
+			c.Run(2)
+			if actual := c.PC == 100; actual != tc.want {
+				t.Errorf("Jump: %v, want: %v", actual, tc.want)
+			}
+		})
+	}
+}
+
+func TestRunMax(t *testing.T) {
+	asm := `
+		@R0
+		D=M
+		@R1
+		D=D-M
+		@OUTPUT_FIRST
+		D;JGT
+		@R1
+		D=M
+		@OUTPUT_D
+		0;JMP
+	(OUTPUT_FIRST)
+		@R0
+		D=M
+	(OUTPUT_D)
+		@R2
+		M=D
+	(INFINITE_LOOP)
+		@INFINITE_LOOP
+		0;JMP
+	`
+	testCases := []struct{ r0, r1, want uint16 }{
+		{r0: 3, r1: 5, want: 5},
+		{r0: 15, r1: 5, want: 15},
+		{r0: 7, r1: 7, want: 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("Max(%d, %d)", tc.r0, tc.r1), func(t *testing.T) {
+			c := loadAsm(t, asm)
+			c.RAM[0], c.RAM[1] = tc.r0, tc.r1
+			c.Run(100)
+			if c.RAM[2] != tc.want {
+				t.Errorf("Actual: %v, want: %v", c.RAM[2], tc.want)
+			}
+		})
+	}
+}
+
+func TestDestUsesOldA(t *testing.T) {
+	c := loadAsm(t, "@10\nAM=A+1;JMP")
+	c.Run(2)
+	if c.RAM[10] != 11 || c.A != 11 || c.PC != 10 {
+		t.Errorf("RAM[10]: %v, A: %v, PC: %v; want 11, 11, 10", c.RAM[10], c.A, c.PC)
+	}
+}
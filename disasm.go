@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/verybigtuple/hackassembler/disasm"
+)
+
+// disasmMain runs "disasm" command with its arguments and returns the exit code
+func disasmMain(args []string) int {
+	fs := flag.NewFlagSet("disasm", flag.ExitOnError)
+	inFileFlag := fs.String("in", "", "Input file with binary code. Usually a file *.hack")
+	outFileFlag := fs.String("out", "", "Output file with hack assembler. If it is not set, stdout is used")
+	labelsFlag := fs.Bool("labels", false, "Synthesize labels for jump targets")
+	fs.Parse(args)
+
+	if *inFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Input file is not set")
+		return fileError
+	}
+
+	inF, err := os.Open(*inFileFlag)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot open input file: %v\n", err)
+		return fileError
+	}
+	defer inF.Close()
+
+	lines, err := disasm.Disassemble(inF, disasm.Options{Labels: *labelsFlag})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot read input file: %v\n", err)
+		return fileError
+	}
+
+	var out io.Writer = os.Stdout
+	if *outFileFlag != "" {
+		outF, err := createFile(*outFileFlag)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return fileError
+		}
+		defer outF.Close()
+		out = outF
+	}
+
+	if err := disasm.Write(out, lines); err != nil {
+		fmt.Fprintf(os.Stderr, "Cannot write output: %v\n", err)
+		return fileError
+	}
+
+	// Invalid words are only flagged, so they do not change the exit code
+	for _, l := range lines {
+		if l.Err != nil {
+			fmt.Fprintf(os.Stderr, "%s:%d: warning: %v\n", *inFileFlag, l.Line, l.Err)
+		}
+	}
+	return 0
+}
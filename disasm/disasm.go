@@ -0,0 +1,149 @@
+// Package disasm translates Hack binary code back into assembler
+package disasm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+const labelPrefix = "L"
+
+// Options of Disassemble
+type Options struct {
+	// Labels turns on synthesizing of labels for jump targets
+	Labels bool
+}
+
+// Line is a disassembled machine word
+type Line struct {
+	// Addr is a ROM address of the word
+	Addr int
+	// Line is a number of the line in the binary source
+	Line int
+	Word code.Word
+	// Text is a disassembled instruction
+	Text string
+	// Label is set if the address is a jump target
+	Label string
+	// Err is set if the word cannot be decoded
+	Err error
+}
+
+// Disassemble reads binary words (one word per line as in *.hack files) and returns
+// disassembled lines. Words that cannot be decoded have Err and are not the reason to stop,
+// but a line that is not a binary word is
+func Disassemble(r io.Reader, opts Options) ([]Line, error) {
+	var words []code.Word
+	var lineNums []int
+
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			continue
+		}
+		w, err := code.ParseWord(s)
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+		}
+		words = append(words, w)
+		lineNums = append(lineNums, lineNum)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	lines := DisassembleWords(words, opts)
+	for i := range lineNums {
+		lines[i].Line = lineNums[i]
+	}
+	return lines, nil
+}
+
+// DisassembleWords returns disassembled lines of machine words
+func DisassembleWords(words []code.Word, opts Options) []Line {
+	lines := make([]Line, 0, len(words))
+	for addr, w := range words {
+		l := Line{Addr: addr, Line: addr + 1, Word: w}
+		l.Text, l.Err = decodeWord(w)
+		lines = append(lines, l)
+	}
+
+	if opts.Labels {
+		lines = addLabels(lines)
+	}
+	return lines
+}
+
+func decodeWord(w code.Word) (string, error) {
+	if w.IsAInstr() {
+		ai, err := code.DecodeAInstr(w)
+		if err != nil {
+			return "", err
+		}
+		return ai.String(), nil
+	}
+
+	ci, err := code.DecodeCInstr(w)
+	if err != nil {
+		return "", err
+	}
+	return ci.String(), nil
+}
+
+// addLabels finds A-Instructions followed by jumps and replaces their addresses by labels.
+// If a label points after the last instruction, a line with only the label is added
+func addLabels(lines []Line) []Line {
+	labels := make(map[int]string)
+	for i := 0; i+1 < len(lines); i++ {
+		if lines[i].Err != nil || lines[i+1].Err != nil {
+			continue
+		}
+		if !lines[i].Word.IsAInstr() || lines[i+1].Word.IsAInstr() || lines[i+1].Word.Jump() == 0 {
+			continue
+		}
+
+		// A label may point right after the last instruction
+		addr := lines[i].Word.Value()
+		if addr > len(lines) {
+			continue
+		}
+		name := labelPrefix + strconv.Itoa(addr)
+		labels[addr] = name
+		lines[i].Text = parser.AInstruction{IsVar: true, Value: name}.String()
+	}
+
+	for i := range lines {
+		lines[i].Label = labels[lines[i].Addr]
+	}
+	if name, ok := labels[len(lines)]; ok {
+		lines = append(lines, Line{Addr: len(lines), Label: name})
+	}
+	return lines
+}
+
+// Write writes lines as assembler code. Words that cannot be decoded are written as comments
+func Write(w io.Writer, lines []Line) error {
+	bw := bufio.NewWriter(w)
+	for _, l := range lines {
+		if l.Label != "" {
+			fmt.Fprintf(bw, "(%s)\n", l.Label)
+		}
+		if l.Err != nil {
+			fmt.Fprintf(bw, "// INVALID %v: %v\n", l.Word, l.Err)
+			continue
+		}
+		if l.Text != "" {
+			fmt.Fprintln(bw, l.Text)
+		}
+	}
+	return bw.Flush()
+}
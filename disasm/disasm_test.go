@@ -0,0 +1,75 @@
+package disasm
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDisassemble(t *testing.T) {
+	hack := `
+		0000000000000010
+		1110110000010000
+		0000000000000100
+		1110001100000001
+		1110101010000111
+		0000000000000011
+		1110101010000111
+		1000000000000000
+	`
+	testCases := []struct {
+		opts Options
+		want string
+	}{
+		{
+			opts: Options{},
+			want: `@2
+D=A
+@4
+D;JGT
+0;JMP
+@3
+0;JMP
+// INVALID 1000000000000000: '1000000000000000' has wrong C-Instruction prefix
+`,
+		},
+		{
+			opts: Options{Labels: true},
+			want: `@2
+D=A
+@L4
+(L3)
+D;JGT
+(L4)
+0;JMP
+@L3
+0;JMP
+// INVALID 1000000000000000: '1000000000000000' has wrong C-Instruction prefix
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			lines, err := Disassemble(strings.NewReader(hack), tc.opts)
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			sb := strings.Builder{}
+			if err := Write(&sb, lines); err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			if sb.String() != tc.want {
+				t.Errorf("Actual:\n%v\nwant:\n%v", sb.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestDisassembleError(t *testing.T) {
+	hack := "0000000000000010\n111011000001000\n"
+	if _, err := Disassemble(strings.NewReader(hack), Options{}); err == nil {
+		t.Errorf("Error was not arisen as expected")
+	}
+}
@@ -1,199 +1,316 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/verybigtuple/hackassembler/assembler"
 	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/output"
 	"github.com/verybigtuple/hackassembler/parser"
 )
 
 const (
-	initCodeSize = 1000
-
 	// Exit Codes
 	parserError = -1
 	codeError   = -2
 	fileError   = -3
+	testError   = -4
+	linkError   = -5
 	otherError  = -99
 )
 
-func clearLine(s string) string {
-	return strings.Trim(s, " \t\r\n")
+func run(names []string, opts assembler.Options, out io.Writer, format output.Writer) (*assembler.Program, error) {
+	prog, err := assembler.AssembleFiles(names, opts)
+	if err != nil {
+		return prog, err
+	}
+
+	return prog, format.Write(out, prog.Words)
 }
 
-type codeReader struct {
-	input     *bufio.Reader
-	LineCount int
+// runObj assembles files as a relocatable module and writes its object file
+func runObj(names []string, opts assembler.Options, out io.Writer, objName string) (*assembler.Program, error) {
+	opts.Relocatable = true
+	prog, err := assembler.AssembleFiles(names, opts)
+	if err != nil {
+		return prog, err
+	}
+
+	obj, err := prog.Object(objName)
+	if err != nil {
+		return prog, err
+	}
+	return prog, obj.WriteJSON(out)
 }
 
-func newCodeReader(in *bufio.Reader) *codeReader {
-	return &codeReader{input: in}
+// objectName returns the module name of an object file: the file name without extension
+func objectName(fileName string) string {
+	base := filepath.Base(fileName)
+	return strings.TrimSuffix(base, filepath.Ext(base))
 }
 
-func (r *codeReader) readNextLine() (string, error) {
-	line, err := r.input.ReadString('\n')
-	if err != nil {
-		return "", err
+// printError prints an error in format "file.asm:123:5: message" followed by the source line
+func printError(err error, loc parser.Location) {
+	fmt.Fprintln(os.Stderr, err)
+	if loc.Source != "" {
+		fmt.Fprintf(os.Stderr, "\t%s\n", loc.Source)
+	}
+	// Calls of macros are shown below lines of their bodies
+	for ; loc.Macro != "" && loc.Parent != nil; loc = *loc.Parent {
+		fmt.Fprintf(os.Stderr, "\t%s\n", loc.Parent.Source)
 	}
-	r.LineCount++
-	normLine := strings.Trim(line, " \t\r\n")
-	return normLine, nil
 }
 
-// readNextCodeLine skips all comment lines and empty line and read next instruction or label line
-func (r *codeReader) readNextCodeLine() (string, error) {
-	for {
-		line, err := r.readNextLine()
-		if err != nil {
-			return "", err
+// printErrors prints all errors from assembler.ErrorList and returns the exit code.
+// Parsing errors take precedence over encoding ones
+func printErrors(err error) int {
+	var list assembler.ErrorList
+	if !errors.As(err, &list) {
+		list = assembler.ErrorList{err}
+	}
+
+	exitCode := 0
+	setCode := func(c int) {
+		if exitCode == 0 || c > exitCode {
+			exitCode = c
 		}
-		if len(line) > 0 && !parser.IsCommentLine(line) {
-			return line, nil
+	}
+	for _, e := range list {
+		switch e := e.(type) {
+		case *parser.ParseError:
+			printError(e, e.Loc)
+			setCode(parserError)
+		case *code.EncoderError:
+			printError(e, e.Loc)
+			setCode(codeError)
+		case *assembler.LinkError:
+			fmt.Fprintln(os.Stderr, e)
+			setCode(linkError)
+		default:
+			if !errors.Is(e, assembler.ErrTooManyErrors) {
+				e = fmt.Errorf("Unknown Error: %w", e)
+				setCode(otherError)
+			}
+			fmt.Fprintln(os.Stderr, e)
 		}
 	}
+	return exitCode
 }
 
-// readAsmCode reads code lines, adds all labels to Symbol table and retruns all asm lines
-// without spaces and comments as []string
-func readAsmCode(in *bufio.Reader, st *code.SymbolTable) ([]string, error) {
-	asmLines := make([]string, 0, initCodeSize)
-
-	asmReader := newCodeReader(in)
-	labelParser := parser.NewLabelParser()
-
-	romCount := 0
-	for {
-		line, err := asmReader.readNextCodeLine()
+// inputFiles returns names of input files. A directory is replaced with all its *.asm files
+// sorted by name
+func inputFiles(names []string) ([]string, error) {
+	files := make([]string, 0, len(names))
+	for _, name := range names {
+		info, err := os.Stat(name)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("Input file %s is not found", name)
+		}
 		if err != nil {
-			return asmLines, nil
+			return nil, fmt.Errorf("Cannot open input file: %v", err)
+		}
+		if !info.IsDir() {
+			files = append(files, name)
+			continue
 		}
 
-		if parser.IsLabelLine(line) {
-			label, err := labelParser.Parse(line)
-			if err != nil {
-				return nil, err
-			}
-			st.AddLabel(label.Value, romCount)
-		} else {
-			asmLines = append(asmLines, line)
-			// asmLines[romCount] = line
-			romCount++
+		dirFiles, err := filepath.Glob(filepath.Join(name, "*.asm"))
+		if err != nil {
+			return nil, err
+		}
+		if len(dirFiles) == 0 {
+			return nil, fmt.Errorf("Directory %s has no *.asm files", name)
 		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
 	}
+	return files, nil
 }
 
-func encodeAsm(asmCode []string, st *code.SymbolTable) ([]string, error) {
-	encoded := make([]string, 0, len(asmCode))
-	aParcer := parser.NewAParser()
-	cParser := parser.NewCParser()
-
-	for _, v := range asmCode {
-		if parser.IsAInstrLine(v) {
-			//TODO: What the hell?
-			ai, err := aParcer.Parse(v)
-			if err != nil {
-				return nil, err
-			}
-			encA, err := code.EncodeAInstr(*ai, st)
-			if err != nil {
-				return nil, err
-			}
-			encoded = append(encoded, encA)
-		} else {
-			ci, err := cParser.Parse(v)
-			if err != nil {
-				return nil, err
-			}
-			encC, err := code.EncodeCInstr(*ci)
-			if err != nil {
-				return nil, err
-			}
-			encoded = append(encoded, encC)
+// stringList is a flag that can be set several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// defineList is a flag "NAME=value" that can be set several times. "NAME" means "NAME=1"
+type defineList map[string]int
+
+func (d defineList) String() string {
+	return fmt.Sprint(map[string]int(d))
+}
+
+func (d defineList) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if !parser.IsSymbolName(parts[0]) {
+		return fmt.Errorf("'%s' is not a valid constant name", parts[0])
+	}
+	val := 1
+	if len(parts) == 2 {
+		var err error
+		if val, err = parser.ParseNumber(parts[1]); err != nil {
+			return fmt.Errorf("'%s' is not a valid value: %v", parts[1], err)
 		}
 	}
-	return encoded, nil
+	d[parts[0]] = val
+	return nil
 }
 
-func run(in *bufio.Reader, out *bufio.Writer) error {
-	symbolTable := code.NewSymbolTable()
+// createFile creates an output file with all its parent dirs. If the file exists, it is truncated
+func createFile(name string) (*os.File, error) {
+	parentDir := filepath.Dir(name)
+	if err := os.MkdirAll(parentDir, os.ModePerm); err != nil {
+		return nil, fmt.Errorf("Cannot create dir %s: %v", parentDir, err)
+	}
 
-	codeLines, err := readAsmCode(in, symbolTable)
+	f, err := os.OpenFile(name, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.ModePerm)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("Cannot open output file: %v", err)
 	}
+	return f, nil
+}
 
-	encLines, err := encodeAsm(codeLines, symbolTable)
+// writeFile creates a file and writes it by the function write
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := createFile(name)
 	if err != nil {
 		return err
 	}
+	defer f.Close()
 
-	for _, cl := range encLines {
-		out.WriteString(cl + "\n")
+	if err := write(f); err != nil {
+		return fmt.Errorf("Cannot write file %s: %v", name, err)
 	}
-	out.Flush()
-	return nil
+	return f.Close()
 }
 
 func main() {
-	inFileFlag := flag.String("in", "", "Input file with hack assembler. Usually has extension *.asm")
+	// Commands other than assembling are set by the first argument
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "disasm":
+			os.Exit(disasmMain(os.Args[2:]))
+		case "run":
+			os.Exit(runMain(os.Args[2:]))
+		case "test":
+			os.Exit(testMain(os.Args[2:]))
+		case "link":
+			os.Exit(linkMain(os.Args[2:]))
+		}
+	}
+
+	inFileFlag := flag.String("in", "", "Input file with hack assembler. Usually has extension *.asm. "+
+		"More files or directories can be set as arguments after flags")
 	outFileFlag := flag.String("out", "", "Output file with binary code. Usually a file *.hack")
+	formatFlag := flag.String("format", "hack", "Format of the output file: "+strings.Join(output.Formats(), ", "))
+	maxErrorsFlag := flag.Int("maxerrors", 10, "Max number of reported errors. 0 means no limit")
+	listFileFlag := flag.String("list", "", "Optional listing file with addresses, binary code and source lines")
+	symFileFlag := flag.String("symbols", "", "Optional symbol table file: *.json or plain text *.sym")
+	mapFileFlag := flag.String("sourcemap", "", "Optional JSON file mapping ROM addresses to source lines")
+	dataFileFlag := flag.String("data", "", "Optional RAM image file (*.hack format) with data of .data directives. "+
+		"If it is set, no instructions writing the data are generated")
+	pseudoFlag := flag.Bool("pseudo", false, "Enable pseudo-instructions: mov, goto, inc, dec, push, pop, jz, jnz, jgt, jge, jlt, jle")
+	objFlag := flag.Bool("obj", false, "Write a relocatable object file for the link command instead of binary code")
+	var includeDirs stringList
+	flag.Var(&includeDirs, "I", "Directory searched for included files. Can be set several times")
+	defines := defineList{}
+	flag.Var(defines, "D", "Constant NAME=value for conditional assembly. NAME means NAME=1. Can be set several times")
 
 	flag.Parse()
 
-	if *inFileFlag == "" {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Input file is not set", *inFileFlag))
-		os.Exit(fileError)
+	var inFiles []string
+	if *inFileFlag != "" {
+		inFiles = append(inFiles, *inFileFlag)
 	}
-
-	if *outFileFlag == "" {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Output file is not set", *inFileFlag))
+	inFiles, err := inputFiles(append(inFiles, flag.Args()...))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(fileError)
 	}
-
-	if _, err := os.Stat(*inFileFlag); os.IsNotExist(err) {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Input file %s is not found", *inFileFlag))
+	if len(inFiles) == 0 {
+		fmt.Fprintln(os.Stderr, "Input file is not set")
 		os.Exit(fileError)
 	}
 
-	inF, err := os.OpenFile(*inFileFlag, os.O_RDONLY, os.ModePerm)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Cannot open input file: %v", err))
+	if *outFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Output file is not set")
 		os.Exit(fileError)
 	}
-	defer inF.Close()
 
-	outParentDir := filepath.Dir(*outFileFlag)
-	if err := os.MkdirAll(outParentDir, os.ModePerm); err != nil {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Cannot create dir %s: %v", outParentDir, err))
-		os.Exit(fileError)
+	format, err := output.ForFormat(*formatFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(otherError)
 	}
 
-	outF, err := os.OpenFile(*outFileFlag, os.O_WRONLY|os.O_CREATE, os.ModePerm)
+	outF, err := createFile(*outFileFlag)
 	if err != nil {
-		fmt.Fprintln(os.Stderr, fmt.Sprintf("Cannot open output file: %v", err))
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(fileError)
 	}
 	defer outF.Close()
 
-	inReader := bufio.NewReader(inF)
-	outWriter := bufio.NewWriter(outF)
-	err = run(inReader, outWriter)
+	opts := assembler.Options{
+		MaxErrors:   *maxErrorsFlag,
+		IncludeDirs: includeDirs,
+		Defines:     defines,
+		DataImage:   *dataFileFlag != "",
+		Pseudo:      *pseudoFlag,
+	}
+	var prog *assembler.Program
+	if *objFlag {
+		prog, err = runObj(inFiles, opts, outF, objectName(*outFileFlag))
+	} else {
+		prog, err = run(inFiles, opts, outF, format)
+	}
 	if err != nil {
-		switch e := err.(type) {
-		case *parser.ParseError:
-			fmt.Fprintln(os.Stderr, fmt.Sprintf("Parsing Error: %v", e))
-			os.Exit(parserError)
-		case *code.EncoderError:
-			fmt.Fprintln(os.Stderr, fmt.Sprintf("Encoding Error: %v", e))
-			os.Exit(codeError)
-		default:
-			fmt.Fprintln(os.Stderr, fmt.Sprintf("Unknown Error: %v", e))
-			os.Exit(otherError)
+		os.Exit(printErrors(err))
+	}
+
+	if *dataFileFlag != "" {
+		hack, _ := output.ForFormat("hack")
+		write := func(w io.Writer) error { return hack.Write(w, prog.DataImage()) }
+		if err := writeFile(*dataFileFlag, write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(fileError)
+		}
+	}
+
+	if *listFileFlag != "" {
+		if err := writeFile(*listFileFlag, prog.WriteListing); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(fileError)
+		}
+	}
+
+	if *symFileFlag != "" {
+		write := prog.Symbols.WriteSym
+		if filepath.Ext(*symFileFlag) == ".json" {
+			write = prog.Symbols.WriteJSON
+		}
+		if err := writeFile(*symFileFlag, write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(fileError)
+		}
+	}
+
+	if *mapFileFlag != "" {
+		if err := writeFile(*mapFileFlag, prog.SourceMap().WriteJSON); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(fileError)
 		}
 	}
 }
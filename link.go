@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/assembler"
+	"github.com/verybigtuple/hackassembler/output"
+)
+
+// linkMain runs "link" command with its arguments and returns the exit code.
+// Object files are set as arguments after flags
+func linkMain(args []string) int {
+	fs := flag.NewFlagSet("link", flag.ExitOnError)
+	outFileFlag := fs.String("out", "", "Output file with binary code. Usually a file *.hack")
+	formatFlag := fs.String("format", "hack", "Format of the output file: "+strings.Join(output.Formats(), ", "))
+	maxErrorsFlag := fs.Int("maxerrors", 10, "Max number of reported errors. 0 means no limit")
+	symFileFlag := fs.String("symbols", "", "Optional symbol table file: *.json or plain text *.sym")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Object files are not set")
+		return fileError
+	}
+	if *outFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Output file is not set")
+		return fileError
+	}
+
+	format, err := output.ForFormat(*formatFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return otherError
+	}
+
+	objs := make([]*assembler.Object, 0, fs.NArg())
+	for _, name := range fs.Args() {
+		obj, err := readObject(name)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return fileError
+		}
+		objs = append(objs, obj)
+	}
+
+	prog, err := assembler.Link(objs, assembler.Options{MaxErrors: *maxErrorsFlag})
+	if err != nil {
+		return printErrors(err)
+	}
+
+	if err := writeFile(*outFileFlag, func(w io.Writer) error { return format.Write(w, prog.Words) }); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return fileError
+	}
+
+	if *symFileFlag != "" {
+		write := prog.Symbols.WriteSym
+		if filepath.Ext(*symFileFlag) == ".json" {
+			write = prog.Symbols.WriteJSON
+		}
+		if err := writeFile(*symFileFlag, write); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return fileError
+		}
+	}
+	return 0
+}
+
+// readObject reads an object file
+func readObject(name string) (*assembler.Object, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open object file: %v", err)
+	}
+	defer f.Close()
+
+	obj, err := assembler.ReadObject(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return obj, nil
+}
@@ -0,0 +1,86 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+const logisimLineSize = 8 // words in a line of Logisim image
+
+// romImage returns words padded with zeros or truncated to the ROM size
+func romImage(words []code.Word) []code.Word {
+	img := make([]code.Word, code.ROMSize)
+	copy(img, words)
+	return img
+}
+
+// writeReadmemb writes the ROM image for Verilog $readmemb: one binary word per line
+func writeReadmemb(w io.Writer, words []code.Word) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "// Hack ROM image for $readmemb")
+	for _, word := range romImage(words) {
+		fmt.Fprintln(bw, word)
+	}
+	return bw.Flush()
+}
+
+// writeReadmemh writes the ROM image for Verilog $readmemh: one hex word per line
+func writeReadmemh(w io.Writer, words []code.Word) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "// Hack ROM image for $readmemh")
+	for _, word := range romImage(words) {
+		fmt.Fprintf(bw, "%04X\n", uint16(word))
+	}
+	return bw.Flush()
+}
+
+// writeVHDL writes the ROM image as a VHDL package with the constant ROM.
+// Words after the program are set by "others"
+func writeVHDL(w io.Writer, words []code.Word) error {
+	if len(words) > code.ROMSize {
+		words = words[:code.ROMSize]
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "-- Hack ROM image")
+	fmt.Fprintln(bw, "library ieee;")
+	fmt.Fprintln(bw, "use ieee.std_logic_1164.all;")
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "package hack_rom is")
+	fmt.Fprintf(bw, "  type rom_type is array (0 to %d) of std_logic_vector(15 downto 0);\n", code.ROMSize-1)
+	fmt.Fprintln(bw, "  constant ROM : rom_type := (")
+	for addr, word := range words {
+		fmt.Fprintf(bw, "    %d => x\"%04X\",\n", addr, uint16(word))
+	}
+	fmt.Fprintln(bw, "    others => x\"0000\"")
+	fmt.Fprintln(bw, "  );")
+	fmt.Fprintln(bw, "end package hack_rom;")
+	return bw.Flush()
+}
+
+// writeLogisim writes the ROM image in Logisim "v2.0 raw" format. Trailing zeros
+// are written as a run "N*0"
+func writeLogisim(w io.Writer, words []code.Word) error {
+	img := romImage(words)
+	last := len(img)
+	for last > 0 && img[last-1] == 0 {
+		last--
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "v2.0 raw")
+	for i, word := range img[:last] {
+		sep := " "
+		if (i+1)%logisimLineSize == 0 || i == last-1 {
+			sep = "\n"
+		}
+		fmt.Fprintf(bw, "%x%s", uint16(word), sep)
+	}
+	if zeros := len(img) - last; zeros > 0 {
+		fmt.Fprintf(bw, "%d*0\n", zeros)
+	}
+	return bw.Flush()
+}
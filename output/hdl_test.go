@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+func TestRomImage(t *testing.T) {
+	testCases := []int{0, 3, code.ROMSize, code.ROMSize + 10}
+
+	for _, n := range testCases {
+		img := romImage(make([]code.Word, n))
+		if len(img) != code.ROMSize {
+			t.Errorf("Image len of %d words: %v; want %v", n, len(img), code.ROMSize)
+		}
+	}
+}
+
+func TestHDLWriters(t *testing.T) {
+	words := []code.Word{0x0002, 0xEC10}
+
+	testCases := []struct {
+		format string
+		head   string
+		lines  int
+	}{
+		{
+			format: "readmemb",
+			head:   "// Hack ROM image for $readmemb\n0000000000000010\n1110110000010000\n0000000000000000\n",
+			lines:  code.ROMSize + 1,
+		},
+		{
+			format: "readmemh",
+			head:   "// Hack ROM image for $readmemh\n0002\nEC10\n0000\n",
+			lines:  code.ROMSize + 1,
+		},
+		{
+			format: "vhdl",
+			head:   "-- Hack ROM image\n",
+			lines:  12,
+		},
+		{
+			format: "logisim",
+			head:   "v2.0 raw\n2 ec10\n32766*0\n",
+			lines:  3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			w, err := ForFormat(tc.format)
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			var buf bytes.Buffer
+			if err := w.Write(&buf, words); err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			if !strings.HasPrefix(buf.String(), tc.head) {
+				t.Errorf("Actual does not start with %q", tc.head)
+			}
+			if lines := strings.Count(buf.String(), "\n"); lines != tc.lines {
+				t.Errorf("Lines: %v; want %v", lines, tc.lines)
+			}
+		})
+	}
+}
+
+func TestWriteVHDL(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeVHDL(&buf, []code.Word{0x0002, 0xEC10}); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	for _, want := range []string{"0 => x\"0002\",\n", "1 => x\"EC10\",\n", "others => x\"0000\"\n", "(0 to 32767)"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("VHDL does not contain %q:\n%s", want, buf.String())
+		}
+	}
+}
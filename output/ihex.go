@@ -0,0 +1,46 @@
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+const (
+	ihexRecordSize = 16 // bytes of data in a record
+	ihexData       = 0x00
+	ihexEOF        = 0x01
+)
+
+// writeIntelHex writes words in Intel HEX format. Words are big-endian,
+// addresses of records are byte addresses, i.e. a word address multiplied by 2
+func writeIntelHex(w io.Writer, words []code.Word) error {
+	data := make([]byte, 0, len(words)*2)
+	for _, word := range words {
+		data = append(data, byte(word>>8), byte(word))
+	}
+
+	bw := bufio.NewWriter(w)
+	for addr := 0; addr < len(data); addr += ihexRecordSize {
+		end := addr + ihexRecordSize
+		if end > len(data) {
+			end = len(data)
+		}
+		writeIhexRecord(bw, addr, ihexData, data[addr:end])
+	}
+	writeIhexRecord(bw, 0, ihexEOF, nil)
+	return bw.Flush()
+}
+
+// writeIhexRecord writes a record ":LLAAAATT<data>CC"
+func writeIhexRecord(w *bufio.Writer, addr int, recType byte, data []byte) {
+	sum := byte(len(data)) + byte(addr>>8) + byte(addr) + recType
+	fmt.Fprintf(w, ":%02X%04X%02X", len(data), addr, recType)
+	for _, b := range data {
+		fmt.Fprintf(w, "%02X", b)
+		sum += b
+	}
+	fmt.Fprintf(w, "%02X\n", byte(-int(sum)))
+}
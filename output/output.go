@@ -0,0 +1,85 @@
+// Package output writes machine words of a Hack program in different file formats
+package output
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+// Writer writes machine words in a file format
+type Writer interface {
+	Write(w io.Writer, words []code.Word) error
+}
+
+// WriterFunc is an adapter to use a function as Writer
+type WriterFunc func(w io.Writer, words []code.Word) error
+
+// Write calls f(w, words)
+func (f WriterFunc) Write(w io.Writer, words []code.Word) error {
+	return f(w, words)
+}
+
+var writers = map[string]Writer{
+	"hack": WriterFunc(writeHack),
+	"bin":  WriterFunc(writeBin),
+	"hex":  WriterFunc(writeHex),
+	"ihex": WriterFunc(writeIntelHex),
+
+	// ROM images for HDL
+	"readmemb": WriterFunc(writeReadmemb),
+	"readmemh": WriterFunc(writeReadmemh),
+	"vhdl":     WriterFunc(writeVHDL),
+	"logisim":  WriterFunc(writeLogisim),
+}
+
+// Formats returns names of all supported formats
+func Formats() []string {
+	names := make([]string, 0, len(writers))
+	for name := range writers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForFormat returns Writer of the format by its name
+func ForFormat(name string) (Writer, error) {
+	w, ok := writers[name]
+	if !ok {
+		return nil, fmt.Errorf("Unknown format '%s'. Supported formats: %s", name, strings.Join(Formats(), ", "))
+	}
+	return w, nil
+}
+
+// writeHack writes words as text lines of 16 binary digits
+func writeHack(w io.Writer, words []code.Word) error {
+	bw := bufio.NewWriter(w)
+	for _, word := range words {
+		fmt.Fprintln(bw, word)
+	}
+	return bw.Flush()
+}
+
+// writeBin writes words as raw big-endian 16-bit numbers
+func writeBin(w io.Writer, words []code.Word) error {
+	bw := bufio.NewWriter(w)
+	if err := binary.Write(bw, binary.BigEndian, words); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// writeHex writes words as text lines of 4 hex digits
+func writeHex(w io.Writer, words []code.Word) error {
+	bw := bufio.NewWriter(w)
+	for _, word := range words {
+		fmt.Fprintf(bw, "%04X\n", uint16(word))
+	}
+	return bw.Flush()
+}
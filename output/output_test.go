@@ -0,0 +1,78 @@
+package output
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/verybigtuple/hackassembler/code"
+)
+
+func TestWriters(t *testing.T) {
+	words := []code.Word{0x0002, 0xEC10, 0x0003}
+
+	testCases := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "hack",
+			want:   "0000000000000010\n1110110000010000\n0000000000000011\n",
+		},
+		{
+			format: "bin",
+			want:   "\x00\x02\xEC\x10\x00\x03",
+		},
+		{
+			format: "hex",
+			want:   "0002\nEC10\n0003\n",
+		},
+		{
+			format: "ihex",
+			want:   ":060000000002EC100003F9\n:00000001FF\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			w, err := ForFormat(tc.format)
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			var buf bytes.Buffer
+			if err := w.Write(&buf, words); err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			if buf.String() != tc.want {
+				t.Errorf("Actual: %q; want %q", buf.String(), tc.want)
+			}
+		})
+	}
+}
+
+func TestIntelHexRecords(t *testing.T) {
+	// 9 words are 18 bytes, so there are two data records
+	words := make([]code.Word, 9)
+	for i := range words {
+		words[i] = 0xFFFF
+	}
+	want := ":10000000FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF00\n" +
+		":02001000FFFFF0\n" +
+		":00000001FF\n"
+
+	var buf bytes.Buffer
+	if err := writeIntelHex(&buf, words); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+	if buf.String() != want {
+		t.Errorf("Actual: %q; want %q", buf.String(), want)
+	}
+}
+
+func TestForFormatError(t *testing.T) {
+	if _, err := ForFormat("exe"); err == nil {
+		t.Errorf("Error was not arisen as expected")
+	}
+}
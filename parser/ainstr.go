@@ -7,10 +7,16 @@ import (
 	"unicode"
 )
 
-//AInstruction of HackAssembler
+//AInstruction of HackAssembler. If IsExpr is true, Value is a constant expression
 type AInstruction struct {
-	IsVar bool
-	Value string
+	IsVar  bool
+	IsExpr bool
+	Value  string
+}
+
+// String returns A-Instruction as it is written in the code
+func (ai AInstruction) String() string {
+	return string(ainstrLiteral) + ai.Value
 }
 
 //AParser is Paraser for A-Instructions
@@ -19,6 +25,9 @@ type AParser struct {
 	reader   *pRuneReader
 	nextStep func() error
 	strB     strings.Builder
+	line     string
+	startOff int // byte offset of the value after '@'
+	startPos int
 }
 
 //NewAParser returns a pointer to a created AParser
@@ -32,6 +41,8 @@ func NewAParser() *AParser {
 func (p *AParser) Parse(s string) (*AInstruction, error) {
 	p.reader = newPRuneReader(s)
 	p.strB.Reset()
+	p.aInstr = AInstruction{}
+	p.line = s
 
 	p.nextStep = p.checkStart
 	for err := p.nextStep(); !errors.Is(err, errEOP); err = p.nextStep() {
@@ -58,18 +69,25 @@ func (p *AParser) checkStart() error {
 }
 
 func (p *AParser) checkFirst() error {
+	p.startOff = p.reader.Offset()
 	rv, _, err := p.reader.ReadRune()
 	if err != nil {
 		return &ParseError{Pos: p.reader.Pos, Msg: "A-Instruction ends unexpectedly"}
 	}
+	p.startPos = p.reader.Pos
 
-	if !unicode.IsDigit(rv) && !unicode.IsLetter(rv) && rv != '_' {
+	if !isNumberStart(rv) && !unicode.IsLetter(rv) && rv != '_' && !isExprStart(rv) {
 		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected first symbol '%c'", rv)}
 	}
 
-	if unicode.IsDigit(rv) {
+	switch {
+	case isExprStart(rv):
+		p.nextStep = p.readExpr
+	case unicode.IsDigit(rv):
 		p.nextStep = p.readNumber
-	} else {
+	case rv == charLiteral:
+		p.nextStep = p.readChar
+	default:
 		p.nextStep = p.readVar
 	}
 
@@ -77,8 +95,11 @@ func (p *AParser) checkFirst() error {
 	return nil
 }
 
+// readNumber reads a decimal number or a number with a prefix 0x or 0b.
+// The value is checked by the encoder, so here only symbols are checked
 func (p *AParser) readNumber() error {
 	var e error = nil
+	isDigit := unicode.IsDigit
 	for {
 		rv, _, err := p.reader.ReadRune()
 		if err != nil {
@@ -86,19 +107,72 @@ func (p *AParser) readNumber() error {
 			break
 		}
 		if unicode.IsSpace(rv) {
-			p.nextStep = p.readComment
+			p.nextStep = p.readTail
 			break
 		}
-		if !unicode.IsDigit(rv) {
+		if isExprRune(rv) {
+			p.nextStep = p.readExpr
+			return nil
+		}
+		if f := numRuneFunc(p.strB.String() + string(rv)); f != nil && p.strB.Len() == 1 {
+			isDigit = f
+		} else if !isDigit(rv) {
 			return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected character in A-Instruction '%c'", rv)}
 		}
 		p.strB.WriteRune(rv)
 	}
+	if numRuneFunc(p.strB.String()) != nil {
+		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Number literal '%s' has no digits", p.strB.String())}
+	}
 	p.aInstr.IsVar = false
 	p.aInstr.Value = p.strB.String()
 	return e
 }
 
+// readChar reads a character literal up to the closing quote
+func (p *AParser) readChar() error {
+	for closed := false; !closed; {
+		rv, _, err := p.reader.ReadRune()
+		if err != nil {
+			return &ParseError{Pos: p.reader.Pos, Msg: "Character literal is not closed"}
+		}
+		p.strB.WriteRune(rv)
+
+		switch rv {
+		case escapeRune:
+			if rv, _, err = p.reader.ReadRune(); err == nil {
+				p.strB.WriteRune(rv)
+			}
+		case charLiteral:
+			closed = true
+		}
+	}
+
+	if _, err := ParseNumber(p.strB.String()); err != nil {
+		return &ParseError{Pos: p.reader.Pos, Msg: err.Error()}
+	}
+	p.aInstr.IsVar = false
+	p.aInstr.Value = p.strB.String()
+	p.nextStep = p.checkCharEnd
+	return nil
+}
+
+func (p *AParser) checkCharEnd() error {
+	rv, _, err := p.reader.ReadRune()
+	if err != nil {
+		return errEOP
+	}
+	switch {
+	case isExprRune(rv):
+		p.nextStep = p.readExpr
+	case unicode.IsSpace(rv):
+		p.nextStep = p.readTail
+	default:
+		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected character in A-Instruction '%c'", rv)}
+	}
+	return nil
+}
+
 func (p *AParser) readVar() error {
 	var e error = nil
 	for {
@@ -108,9 +182,13 @@ func (p *AParser) readVar() error {
 			break
 		}
 		if unicode.IsSpace(rv) {
-			p.nextStep = p.readComment
+			p.nextStep = p.readTail
 			break
 		}
+		if isExprRune(rv) {
+			p.nextStep = p.readExpr
+			return nil
+		}
 		if !isVarRune(rv) {
 			return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected character in A-Instruction '%c'", rv)}
 		}
@@ -121,6 +199,38 @@ func (p *AParser) readVar() error {
 	return e
 }
 
-func (p *AParser) readComment() error {
-	return checkInlineComment(p.reader)
+// readTail reads spaces after a value. They can be followed by a comment or
+// by an operator of an expression
+func (p *AParser) readTail() error {
+	rv, _, err := p.reader.ReadAfterSpaces()
+	if err != nil {
+		return errEOP
+	}
+	if rv == commentLiteral {
+		if nrv, _, err := p.reader.ReadRune(); err == nil && nrv == commentLiteral {
+			return errEOP
+		}
+	}
+	if !isExprRune(rv) {
+		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected character '%c'", rv)}
+	}
+	p.nextStep = p.readExpr
+	return nil
+}
+
+// readExpr parses the whole value after '@' as a constant expression
+func (p *AParser) readExpr() error {
+	s := p.line[p.startOff:]
+	_, n, err := parseExpr(s)
+	if err != nil {
+		var pe *ParseError
+		if errors.As(err, &pe) {
+			return &ParseError{Pos: p.startPos + pe.Pos - 1, Msg: pe.Msg}
+		}
+		return err
+	}
+	p.aInstr.IsVar = false
+	p.aInstr.IsExpr = true
+	p.aInstr.Value = strings.TrimSpace(s[:n])
+	return errEOP
 }
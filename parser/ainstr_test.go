@@ -75,6 +75,58 @@ func TestAParseRegular(t *testing.T) {
 			operator: "@v123",
 			want:     AInstruction{IsVar: true, Value: "v123"},
 		},
+		{
+			operator: "@0x4000",
+			want:     AInstruction{IsVar: false, Value: "0x4000"},
+		},
+		{
+			operator: "@0XfF //Comment",
+			want:     AInstruction{IsVar: false, Value: "0XfF"},
+		},
+		{
+			operator: "@0b101",
+			want:     AInstruction{IsVar: false, Value: "0b101"},
+		},
+		{
+			operator: "@'A'",
+			want:     AInstruction{IsVar: false, Value: "'A'"},
+		},
+		{
+			operator: "@' ' //Space",
+			want:     AInstruction{IsVar: false, Value: "' '"},
+		},
+		{
+			operator: `@'\''`,
+			want:     AInstruction{IsVar: false, Value: `'\''`},
+		},
+		{
+			operator: "@SCREEN+32",
+			want:     AInstruction{IsExpr: true, Value: "SCREEN+32"},
+		},
+		{
+			operator: "@A-B",
+			want:     AInstruction{IsExpr: true, Value: "A-B"},
+		},
+		{
+			operator: "@A /B",
+			want:     AInstruction{IsExpr: true, Value: "A /B"},
+		},
+		{
+			operator: "@END - 1 // Comment",
+			want:     AInstruction{IsExpr: true, Value: "END - 1"},
+		},
+		{
+			operator: "@-1",
+			want:     AInstruction{IsExpr: true, Value: "-1"},
+		},
+		{
+			operator: "@(ARR+2)*(1<<3)|0x10",
+			want:     AInstruction{IsExpr: true, Value: "(ARR+2)*(1<<3)|0x10"},
+		},
+		{
+			operator: "@'A'+1",
+			want:     AInstruction{IsExpr: true, Value: "'A'+1"},
+		},
 		{
 			operator: "@var //Comment",
 			want:     AInstruction{IsVar: true, Value: "var"},
@@ -114,13 +166,55 @@ func TestAParseError(t *testing.T) {
 			operator: "@A B",
 		},
 		{
-			operator: "@A /B",
+			operator: "@A+",
 		},
 		{
-			operator: "@A-B",
+			operator: "@(A+1",
+		},
+		{
+			operator: "@A+1)",
+		},
+		{
+			operator: "@A<B",
+		},
+		{
+			operator: "@A+1 B",
+		},
+		{
+			operator: "@A%2",
+		},
+		{
+			operator: "@A+0x",
+		},
+		{
+			operator: "@-",
+		},
+		{
+			operator: "@A+1//C",
+		},
+		{
+			operator: "@0x",
+		},
+		{
+			operator: "@0xG",
+		},
+		{
+			operator: "@0b2",
+		},
+		{
+			operator: "@1x0",
+		},
+		{
+			operator: "@'AB'",
+		},
+		{
+			operator: "@'A",
+		},
+		{
+			operator: "@''",
 		},
 		{
-			operator: "@A /_",
+			operator: "@'A'B",
 		},
 	}
 
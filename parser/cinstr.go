@@ -14,6 +14,18 @@ type CIntstruction struct {
 	Jump string
 }
 
+// String returns C-Instruction as it is written in the code, i.e. "dest=comp;jump"
+func (ci CIntstruction) String() string {
+	s := ci.Comp
+	if ci.Dest != "" {
+		s = ci.Dest + string(compDelim) + s
+	}
+	if ci.Jump != "" {
+		s += string(jumpDelim) + ci.Jump
+	}
+	return s
+}
+
 type runeReadSeeker interface {
 	io.RuneReader
 	io.Seeker
@@ -0,0 +1,206 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	directiveLiteral = '.'
+	stringLiteral    = '"'
+)
+
+// Directive is a line that is not translated into an instruction, but controls
+// the assembler, i.e. ".equ ROWWORDS 32". Args is the rest of the line without a comment
+type Directive struct {
+	Name string
+	Args string
+}
+
+// IsDirectiveLine returns true if line starts with a directive prefix
+func IsDirectiveLine(line string) bool {
+	return strings.HasPrefix(line, string(directiveLiteral))
+}
+
+// IsSymbolName returns true if s can be a name of a label, a variable or a constant
+func IsSymbolName(s string) bool {
+	for i, r := range s {
+		if i == 0 && !unicode.IsLetter(r) && r != '_' {
+			return false
+		}
+		if !isVarRune(r) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// SplitArg returns the first argument separated by spaces and the rest of arguments
+func SplitArg(args string) (string, string) {
+	args = strings.TrimSpace(args)
+	i := strings.IndexFunc(args, unicode.IsSpace)
+	if i < 0 {
+		return args, ""
+	}
+	return args[:i], strings.TrimSpace(args[i:])
+}
+
+// DirectiveParser is a parser for directives
+type DirectiveParser struct {
+	directive Directive
+	reader    *pRuneReader
+	nextStep  func() error
+	strB      strings.Builder
+	line      string
+}
+
+// NewDirectiveParser returns a pointer to a new DirectiveParser
+func NewDirectiveParser() *DirectiveParser {
+	dp := DirectiveParser{}
+	dp.strB.Grow(15)
+	return &dp
+}
+
+// Parse returns a parsed directive or an error
+func (p *DirectiveParser) Parse(s string) (*Directive, error) {
+	p.reader = newPRuneReader(s)
+	p.strB.Reset()
+	p.directive = Directive{}
+	p.line = s
+
+	p.nextStep = p.checkStart
+	for err := p.nextStep(); !errors.Is(err, errEOP); err = p.nextStep() {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &p.directive, nil
+}
+
+func (p *DirectiveParser) checkStart() error {
+	rv, _, err := p.reader.ReadAfterSpaces()
+	if err != nil {
+		return errEOP
+	}
+	if rv != directiveLiteral {
+		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected start of directive '%c'", rv)}
+	}
+
+	p.nextStep = p.readName
+	return nil
+}
+
+func (p *DirectiveParser) readName() error {
+	for {
+		off := p.reader.Offset()
+		rv, _, err := p.reader.ReadRune()
+		if err == nil && unicode.IsLetter(rv) {
+			p.strB.WriteRune(rv)
+			continue
+		}
+		if err == nil && !unicode.IsSpace(rv) {
+			return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Unexpected character in directive name '%c'", rv)}
+		}
+		if p.strB.Len() == 0 {
+			return &ParseError{Pos: p.reader.Pos, Msg: "Directive name is expected"}
+		}
+
+		p.directive.Name = p.strB.String()
+		p.directive.Args = strings.TrimSpace(StripComment(p.line[off:]))
+		return errEOP
+	}
+}
+
+// StripComment returns s without an inline comment. A comment must be separated
+// by spaces, "//" in a string or a character literal is not a comment
+func StripComment(s string) string {
+	var quote rune
+	escaped := false
+	prev := ' '
+	for i, r := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && r == escapeRune:
+			escaped = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == stringLiteral || r == charLiteral:
+			quote = r
+		case unicode.IsSpace(prev) && strings.HasPrefix(s[i:], commentPrefix):
+			return s[:i]
+		}
+		prev = r
+	}
+	return s
+}
+
+// ReplaceSymbols returns s where every symbol name is replaced by the result of f.
+// Names of local labels are passed to f with the leading dot.
+// Numbers, character and string literals and comments are not changed
+func ReplaceSymbols(s string, f func(name string) string) string {
+	text := StripComment(s)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		end := i + size
+		switch {
+		case r == stringLiteral || r == charLiteral:
+			for escaped := false; end < len(text); {
+				nr, nsize := utf8.DecodeRuneInString(text[end:])
+				end += nsize
+				if !escaped && nr == r {
+					break
+				}
+				escaped = !escaped && nr == escapeRune
+			}
+		case unicode.IsDigit(r) || unicode.IsLetter(r) || r == '_' || IsLocalName(text[i:]):
+			for end < len(text) {
+				nr, nsize := utf8.DecodeRuneInString(text[end:])
+				if !isVarRune(nr) {
+					break
+				}
+				end += nsize
+			}
+		}
+
+		if unicode.IsLetter(r) || r == '_' || IsLocalName(text[i:]) {
+			sb.WriteString(f(text[i:end]))
+		} else {
+			sb.WriteString(text[i:end])
+		}
+		i = end
+	}
+	sb.WriteString(s[len(text):])
+	return sb.String()
+}
+
+// ParseString returns the value of a string literal in double quotes.
+// Quotes and backslashes in the string are escaped by a backslash
+func ParseString(s string) (string, error) {
+	rs := []rune(s)
+	if len(rs) < 2 || rs[0] != stringLiteral || rs[len(rs)-1] != stringLiteral {
+		return "", fmt.Errorf("Wrong string literal %s", s)
+	}
+
+	var sb strings.Builder
+	for i := 1; i < len(rs)-1; i++ {
+		r := rs[i]
+		switch {
+		case r == escapeRune && i < len(rs)-2 && (rs[i+1] == stringLiteral || rs[i+1] == escapeRune):
+			i++
+			r = rs[i]
+		case r == escapeRune || r == stringLiteral:
+			return "", fmt.Errorf("Wrong string literal %s", s)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String(), nil
+}
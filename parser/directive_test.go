@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDirectiveParse(t *testing.T) {
+	testCases := []struct {
+		line string
+		want Directive
+	}{
+		{line: ".equ N 32", want: Directive{Name: "equ", Args: "N 32"}},
+		{line: "  .equ   N   SCREEN + 1  // Comment", want: Directive{Name: "equ", Args: "N   SCREEN + 1"}},
+		{line: ".endm", want: Directive{Name: "endm"}},
+		{line: ".endm // Comment", want: Directive{Name: "endm"}},
+		{line: `.include "lib//a.asm" // Comment`, want: Directive{Name: "include", Args: `"lib//a.asm"`}},
+		{line: `.string "say \"hi\" //" // Comment`, want: Directive{Name: "string", Args: `"say \"hi\" //"`}},
+		{line: ".word '/' //Comment", want: Directive{Name: "word", Args: "'/'"}},
+	}
+
+	p := NewDirectiveParser()
+	for _, tC := range testCases {
+		t.Run(tC.line, func(t *testing.T) {
+			actual, err := p.Parse(tC.line)
+			if err != nil {
+				t.Errorf("The test returned an exception: %v", err)
+				return
+			}
+			if *actual != tC.want {
+				t.Errorf("Parsed: %+v ; want %+v", *actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestDirectiveParseError(t *testing.T) {
+	testCases := []string{"equ N 1", ".", ". equ", ".equ1 N", ".equ/"}
+
+	p := NewDirectiveParser()
+	for _, tC := range testCases {
+		t.Run(tC, func(t *testing.T) {
+			actual, err := p.Parse(tC)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Errorf("ParseError was not arisen as expected. Actual: %+v, %v", actual, err)
+			}
+		})
+	}
+}
+
+func TestSplitArg(t *testing.T) {
+	testCases := []struct {
+		args, first, rest string
+	}{
+		{args: "N 32", first: "N", rest: "32"},
+		{args: " N\tSCREEN + 1 ", first: "N", rest: "SCREEN + 1"},
+		{args: "N", first: "N", rest: ""},
+		{args: "", first: "", rest: ""},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.args, func(t *testing.T) {
+			first, rest := SplitArg(tC.args)
+			if first != tC.first || rest != tC.rest {
+				t.Errorf("Actual: %q %q; want %q %q", first, rest, tC.first, tC.rest)
+			}
+		})
+	}
+}
+
+func TestReplaceSymbols(t *testing.T) {
+	subst := map[string]string{"x": "SCREEN+1", "LOOP": "M$LOOP.1", "D": "A", ".end": "MAIN.end"}
+	replace := func(name string) string {
+		if v, ok := subst[name]; ok {
+			return v
+		}
+		return name
+	}
+
+	testCases := []struct {
+		s, want string
+	}{
+		{s: "@x", want: "@SCREEN+1"},
+		{s: "(LOOP)", want: "(M$LOOP.1)"},
+		{s: "@x.y+x*0x10", want: "@x.y+SCREEN+1*0x10"},
+		{s: "D=D+1 // D x", want: "A=A+1 // D x"},
+		{s: `.string "x" 'x'`, want: `.string "x" 'x'`},
+		{s: "@.end-1", want: "@MAIN.end-1"},
+		{s: "@x.end", want: "@x.end"},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.s, func(t *testing.T) {
+			if actual := ReplaceSymbols(tC.s, replace); actual != tC.want {
+				t.Errorf("Actual: %q; want %q", actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestParseString(t *testing.T) {
+	testCases := []struct {
+		s, want string
+	}{
+		{s: `""`, want: ""},
+		{s: `"lib/os.asm"`, want: "lib/os.asm"},
+		{s: `"say \"hi\""`, want: `say "hi"`},
+		{s: `"a\\b"`, want: `a\b`},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.s, func(t *testing.T) {
+			actual, err := ParseString(tC.s)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if actual != tC.want {
+				t.Errorf("Actual: %q; want %q", actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestParseStringError(t *testing.T) {
+	testCases := []string{"", `"`, "abc", `"abc`, `"a"b"`, `"a\"`, `'a'`}
+	for _, tC := range testCases {
+		t.Run(tC, func(t *testing.T) {
+			if actual, err := ParseString(tC); err == nil {
+				t.Errorf("Error was not arisen as expected. Actual: %q", actual)
+			}
+		})
+	}
+}
@@ -3,16 +3,61 @@ package parser
 import (
 	"errors"
 	"fmt"
+	"strings"
 )
 
+// Location points to a place in an assembler source. Line and Col start with 1,
+// Source is the whole text of the line
+type Location struct {
+	File   string
+	Line   int
+	Col    int
+	Source string
+	// Macro is the name of the macro the line is expanded from or a directive like ".rept".
+	// Parent is the location of the macro call or of the include directive if Macro is empty
+	Macro  string
+	Parent *Location
+}
+
+// String returns location as "file.asm:123:5" or as "123:5" if File is not set
+func (l Location) String() string {
+	if l.File == "" {
+		return fmt.Sprintf("%d:%d", l.Line, l.Col)
+	}
+	return fmt.Sprintf("%s:%d:%d", l.File, l.Line, l.Col)
+}
+
 // ParseError implements error arisen while parsing Label, A- or C-Intruction.
+// Pos is a position in the parsed string, Loc is set only when the string
+// is known to be a line of a source file
 type ParseError struct {
 	Pos int
 	Msg string
+	Loc Location
+}
+
+// Trace returns all places the line comes from, i.e. " (in macro PUSH called at a.asm:10:1)".
+// It is empty for a line that is read directly from a source file
+func (l Location) Trace() string {
+	var sb strings.Builder
+	for c := l; c.Parent != nil; c = *c.Parent {
+		switch {
+		case strings.HasPrefix(c.Macro, string(directiveLiteral)):
+			fmt.Fprintf(&sb, " (in %s at %v)", c.Macro, *c.Parent)
+		case c.Macro != "":
+			fmt.Fprintf(&sb, " (in macro %s called at %v)", c.Macro, *c.Parent)
+		default:
+			fmt.Fprintf(&sb, " (included from %v)", *c.Parent)
+		}
+	}
+	return sb.String()
 }
 
 func (e *ParseError) Error() string {
-	return fmt.Sprintf("Parsing error at position %d: %s", e.Pos, e.Msg)
+	if e.Loc.Line == 0 {
+		return fmt.Sprintf("Parsing error at position %d: %s", e.Pos, e.Msg)
+	}
+	return fmt.Sprintf("%v: %s%s", e.Loc, e.Msg, e.Loc.Trace())
 }
 
 // Special error to stop state machine
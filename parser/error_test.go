@@ -0,0 +1,47 @@
+package parser
+
+import (
+	"testing"
+)
+
+func TestParseErrorFormat(t *testing.T) {
+	testCases := []struct {
+		err  ParseError
+		want string
+	}{
+		{
+			err:  ParseError{Pos: 3, Msg: "msg"},
+			want: "Parsing error at position 3: msg",
+		},
+		{
+			err:  ParseError{Pos: 3, Msg: "msg", Loc: Location{File: "file.asm", Line: 123, Col: 5}},
+			want: "file.asm:123:5: msg",
+		},
+		{
+			err:  ParseError{Pos: 3, Msg: "msg", Loc: Location{Line: 123, Col: 5}},
+			want: "123:5: msg",
+		},
+		{
+			err: ParseError{Pos: 3, Msg: "msg", Loc: Location{
+				File: "lib.asm", Line: 2, Col: 1, Macro: "PUSH",
+				Parent: &Location{File: "lib.asm", Line: 10, Col: 3, Parent: &Location{File: "main.asm", Line: 1, Col: 1}},
+			}},
+			want: "lib.asm:2:1: msg (in macro PUSH called at lib.asm:10:3) (included from main.asm:1:1)",
+		},
+		{
+			err: ParseError{Pos: 3, Msg: "msg", Loc: Location{
+				File: "main.asm", Line: 4, Col: 1, Macro: ".rept",
+				Parent: &Location{File: "main.asm", Line: 3, Col: 1},
+			}},
+			want: "main.asm:4:1: msg (in .rept at main.asm:3:1)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.want, func(t *testing.T) {
+			if actual := tc.err.Error(); actual != tc.want {
+				t.Errorf("Actual: %v, want: %v", actual, tc.want)
+			}
+		})
+	}
+}
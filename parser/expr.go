@@ -0,0 +1,360 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Operators of constant expressions. Binary operators have the same precedence as in C:
+// "*" and "/" bind tighter than "+" and "-", then shifts, then "&" and "|"
+var binaryPrec = map[string]int{
+	"|":  1,
+	"&":  2,
+	"<<": 3,
+	">>": 3,
+	"+":  4,
+	"-":  4,
+	"*":  5,
+	"/":  5,
+}
+
+const (
+	unaryMinus = "-"
+	openParen  = "("
+	closeParen = ")"
+	maxShift   = strconv.IntSize - 2
+	maxValue   = int(^uint(0) >> 1)
+	minValue   = -maxValue - 1
+)
+
+// errOverflow is returned if a value of an expression does not fit in int
+var errOverflow = errors.New("Integer overflow")
+
+// Expr is a constant expression of an A-Instruction, i.e. SCREEN+32 or (END-1)*2
+type Expr interface {
+	// Eval returns the value of the expression. Values of symbols are returned by lookup
+	Eval(lookup func(name string) (int, error)) (int, error)
+}
+
+// NumberExpr is a number literal
+type NumberExpr int
+
+func (e NumberExpr) Eval(lookup func(string) (int, error)) (int, error) {
+	return int(e), nil
+}
+
+// SymbolExpr is a symbol name
+type SymbolExpr string
+
+func (e SymbolExpr) Eval(lookup func(string) (int, error)) (int, error) {
+	return lookup(string(e))
+}
+
+// UnaryExpr is a unary minus
+type UnaryExpr struct {
+	Op string
+	X  Expr
+}
+
+func (e *UnaryExpr) Eval(lookup func(string) (int, error)) (int, error) {
+	x, err := e.X.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	if x == minValue {
+		return 0, errOverflow
+	}
+	return -x, nil
+}
+
+// BinaryExpr is an operation with two operands
+type BinaryExpr struct {
+	Op   string
+	X, Y Expr
+}
+
+func (e *BinaryExpr) Eval(lookup func(string) (int, error)) (int, error) {
+	x, err := e.X.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+	y, err := e.Y.Eval(lookup)
+	if err != nil {
+		return 0, err
+	}
+
+	switch e.Op {
+	case "+":
+		if (y > 0 && x > maxValue-y) || (y < 0 && x < minValue-y) {
+			return 0, errOverflow
+		}
+		return x + y, nil
+	case "-":
+		if (y < 0 && x > maxValue+y) || (y > 0 && x < minValue+y) {
+			return 0, errOverflow
+		}
+		return x - y, nil
+	case "*":
+		p := x * y
+		if x != 0 && (p/x != y || (x == -1 && y == minValue)) {
+			return 0, errOverflow
+		}
+		return p, nil
+	case "/":
+		if y == 0 {
+			return 0, errors.New("Division by zero")
+		}
+		if x == minValue && y == -1 {
+			return 0, errOverflow
+		}
+		return x / y, nil
+	case "&":
+		return x & y, nil
+	case "|":
+		return x | y, nil
+	}
+
+	if y < 0 || y > maxShift {
+		return 0, fmt.Errorf("Wrong shift count %d", y)
+	}
+	if e.Op == "<<" {
+		if x<<y>>y != x {
+			return 0, errOverflow
+		}
+		return x << y, nil
+	}
+	return x >> y, nil
+}
+
+// Kinds of expression tokens
+const (
+	tokEOF = iota
+	tokNumber
+	tokSymbol
+	tokOp
+)
+
+type exprToken struct {
+	kind  int
+	text  string
+	value int
+	pos   int
+}
+
+// exprLexer splits an expression into tokens. Pos is the count of read runes as in pRuneReader
+type exprLexer struct {
+	s   string
+	off int
+	pos int
+}
+
+func (l *exprLexer) peek() (rune, int) {
+	if l.off >= len(l.s) {
+		return utf8.RuneError, 0
+	}
+	return utf8.DecodeRuneInString(l.s[l.off:])
+}
+
+func (l *exprLexer) next() rune {
+	r, size := l.peek()
+	l.off += size
+	l.pos++
+	return r
+}
+
+// readWhile reads runes while f returns true and returns the read string
+func (l *exprLexer) readWhile(f func(rune) bool) string {
+	start := l.off
+	for r, size := l.peek(); size > 0 && f(r); r, size = l.peek() {
+		l.next()
+	}
+	return l.s[start:l.off]
+}
+
+// isComment returns true if the rest of the line is an inline comment. A comment
+// must be separated from the expression by spaces like in any other instruction
+func (l *exprLexer) isComment() bool {
+	if len(l.s)-l.off < len(commentPrefix) || l.s[l.off:l.off+len(commentPrefix)] != commentPrefix {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(l.s[:l.off])
+	return l.off == 0 || unicode.IsSpace(r)
+}
+
+// token returns the next token. The end of the string and a comment are tokEOF
+func (l *exprLexer) token() (exprToken, error) {
+	l.readWhile(unicode.IsSpace)
+	if l.isComment() {
+		return exprToken{kind: tokEOF, pos: l.pos + 1}, nil
+	}
+
+	r, size := l.peek()
+	if size == 0 {
+		return exprToken{kind: tokEOF, pos: l.pos + 1}, nil
+	}
+
+	start := l.off
+	tok := exprToken{pos: l.pos + 1}
+	switch {
+	case unicode.IsDigit(r):
+		tok.kind = tokNumber
+		tok.text = l.readWhile(func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) })
+	case r == charLiteral:
+		tok.kind = tokNumber
+		l.next()
+		for closed := false; !closed; {
+			if _, size := l.peek(); size == 0 {
+				return tok, &ParseError{Pos: l.pos, Msg: "Character literal is not closed"}
+			}
+			switch l.next() {
+			case escapeRune:
+				l.next()
+			case charLiteral:
+				closed = true
+			}
+		}
+		tok.text = l.s[start:l.off]
+	case unicode.IsLetter(r) || r == '_':
+		tok.kind = tokSymbol
+		tok.text = l.readWhile(isVarRune)
+		return tok, nil
+	default:
+		tok.kind = tokOp
+		l.next()
+		if r == '<' || r == '>' {
+			if nr, _ := l.peek(); nr != r {
+				return tok, &ParseError{Pos: tok.pos, Msg: fmt.Sprintf("Unexpected character '%c' in expression", r)}
+			}
+			l.next()
+		}
+		tok.text = l.s[start:l.off]
+		if _, ok := binaryPrec[tok.text]; !ok && tok.text != openParen && tok.text != closeParen {
+			return tok, &ParseError{Pos: tok.pos, Msg: fmt.Sprintf("Unexpected character '%c' in expression", r)}
+		}
+		return tok, nil
+	}
+
+	n, err := ParseNumber(tok.text)
+	if err != nil {
+		return tok, &ParseError{Pos: tok.pos, Msg: err.Error()}
+	}
+	tok.value = n
+	return tok, nil
+}
+
+// exprParser is a recursive descent parser of expressions
+type exprParser struct {
+	lexer *exprLexer
+	tok   exprToken
+	end   int // byte offset of the end of the expression
+}
+
+func (p *exprParser) next() error {
+	p.end = p.lexer.off
+	tok, err := p.lexer.token()
+	p.tok = tok
+	return err
+}
+
+func (p *exprParser) parseBinary(minPrec int) (Expr, error) {
+	x, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		prec, ok := binaryPrec[p.tok.text]
+		if p.tok.kind != tokOp || !ok || prec < minPrec {
+			return x, nil
+		}
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		y, err := p.parseBinary(prec + 1)
+		if err != nil {
+			return nil, err
+		}
+		x = &BinaryExpr{Op: op, X: x, Y: y}
+	}
+}
+
+func (p *exprParser) parseUnary() (Expr, error) {
+	if p.tok.kind == tokOp && p.tok.text == unaryMinus {
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: unaryMinus, X: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (Expr, error) {
+	tok := p.tok
+	var x Expr
+	switch {
+	case tok.kind == tokNumber:
+		x = NumberExpr(tok.value)
+	case tok.kind == tokSymbol:
+		x = SymbolExpr(tok.text)
+	case tok.kind == tokOp && tok.text == openParen:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		var err error
+		if x, err = p.parseBinary(1); err != nil {
+			return nil, err
+		}
+		if p.tok.kind != tokOp || p.tok.text != closeParen {
+			return nil, &ParseError{Pos: p.tok.pos, Msg: fmt.Sprintf("Expected '%s'", closeParen)}
+		}
+	case tok.kind == tokEOF:
+		return nil, &ParseError{Pos: tok.pos, Msg: "Expression ends unexpectedly"}
+	default:
+		return nil, &ParseError{Pos: tok.pos, Msg: fmt.Sprintf("Unexpected '%s' in expression", tok.text)}
+	}
+	return x, p.next()
+}
+
+// parseExpr parses an expression that can be followed by an inline comment.
+// It returns the expression and the length of its text in bytes
+func parseExpr(s string) (Expr, int, error) {
+	p := exprParser{lexer: &exprLexer{s: s}}
+	if err := p.next(); err != nil {
+		return nil, 0, err
+	}
+	x, err := p.parseBinary(1)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.tok.kind != tokEOF {
+		return nil, 0, &ParseError{Pos: p.tok.pos, Msg: fmt.Sprintf("Unexpected '%s' in expression", p.tok.text)}
+	}
+	return x, p.end, nil
+}
+
+// ParseExpr returns a constant expression over numbers and symbols with operators
+// + - * / & | << >>, parentheses and unary minus
+func ParseExpr(s string) (Expr, error) {
+	x, _, err := parseExpr(s)
+	return x, err
+}
+
+// ExprSymbols returns names of all symbols of the expression in order of their appearance
+func ExprSymbols(e Expr) []string {
+	switch e := e.(type) {
+	case SymbolExpr:
+		return []string{string(e)}
+	case *UnaryExpr:
+		return ExprSymbols(e.X)
+	case *BinaryExpr:
+		return append(ExprSymbols(e.X), ExprSymbols(e.Y)...)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExprEval(t *testing.T) {
+	symbols := map[string]int{"SCREEN": 16384, "END": 10, "i.a": 3}
+	lookup := func(name string) (int, error) {
+		if v, ok := symbols[name]; ok {
+			return v, nil
+		}
+		return 0, fmt.Errorf("Unknown symbol %s", name)
+	}
+
+	testCases := []struct {
+		expr string
+		want int
+	}{
+		{expr: "1", want: 1},
+		{expr: "SCREEN+32", want: 16416},
+		{expr: "END-1", want: 9},
+		{expr: "1+2*3", want: 7},
+		{expr: "(1+2)*3", want: 9},
+		{expr: "10-4-3", want: 3},
+		{expr: "100/10/5", want: 2},
+		{expr: "-END+20", want: 10},
+		{expr: "--1", want: 1},
+		{expr: "1<<4+1", want: 32},
+		{expr: "0xFF&0x0F|0x30", want: 0x3F},
+		{expr: "SCREEN>>1", want: 8192},
+		{expr: " i.a * 'A' ", want: 195},
+		{expr: fmt.Sprintf("-(1<<%d)*2", maxShift), want: minValue},
+		{expr: fmt.Sprintf("-1<<%d", maxShift), want: -1 << maxShift},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.expr, func(t *testing.T) {
+			expr, err := ParseExpr(tC.expr)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			actual, err := expr.Eval(lookup)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if actual != tC.want {
+				t.Errorf("Actual: %d, want: %d", actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestExprEvalError(t *testing.T) {
+	lookup := func(name string) (int, error) {
+		return 0, fmt.Errorf("Unknown symbol %s", name)
+	}
+
+	testCases := []string{
+		"1/0",
+		"1<<-1",
+		"1>>100",
+		"1+UNKNOWN",
+		"65536*65536*65536*65536",
+		fmt.Sprintf("1<<%d", maxShift+1),
+		fmt.Sprintf("(1<<%d)*4+5", maxShift),
+		fmt.Sprintf("(1<<%d)+(1<<%d)", maxShift, maxShift),
+		fmt.Sprintf("-(1<<%d)-(1<<%d)-1-1", maxShift, maxShift),
+		fmt.Sprintf("3<<%d", maxShift),
+		fmt.Sprintf("-(-(1<<%d)*2)", maxShift),
+		fmt.Sprintf("-(1<<%d)*2/-1", maxShift),
+	}
+	for _, tC := range testCases {
+		t.Run(tC, func(t *testing.T) {
+			expr, err := ParseExpr(tC)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if actual, err := expr.Eval(lookup); err == nil {
+				t.Errorf("Error was not arisen as expected. Actual: %d", actual)
+			}
+		})
+	}
+}
+
+func TestParseExprError(t *testing.T) {
+	testCases := []struct {
+		expr string
+		pos  int
+	}{
+		{expr: "", pos: 1},
+		{expr: "1+", pos: 3},
+		{expr: "(1+2", pos: 5},
+		{expr: "1+2)", pos: 4},
+		{expr: "1 2", pos: 3},
+		{expr: "1<2", pos: 2},
+		{expr: "1+0xZ", pos: 3},
+		{expr: "1+'A", pos: 4},
+		{expr: "A # 1", pos: 3},
+		{expr: "*1", pos: 1},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.expr, func(t *testing.T) {
+			_, err := ParseExpr(tC.expr)
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Errorf("ParseError was not arisen as expected: %v", err)
+				return
+			}
+			if pe.Pos != tC.pos {
+				t.Errorf("Error position: %d, want: %d", pe.Pos, tC.pos)
+			}
+		})
+	}
+}
@@ -4,12 +4,14 @@ import (
 	"fmt"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
 const (
 	ainstrLiteral     = '@'
 	startLabelLiteral = '('
 	endLabelLiteral   = ')'
+	localLabelLiteral = '.'
 
 	compDelim = '='
 	jumpDelim = ';'
@@ -23,6 +25,16 @@ func IsLabelLine(line string) bool {
 	return strings.HasPrefix(line, string(startLabelLiteral))
 }
 
+// IsLocalName returns true if s is a name of a local label, i.e. ".loop"
+func IsLocalName(s string) bool {
+	r, size := utf8.DecodeRuneInString(s)
+	if r != localLabelLiteral {
+		return false
+	}
+	r, _ = utf8.DecodeRuneInString(s[size:])
+	return unicode.IsLetter(r) || r == '_'
+}
+
 // IsAInstrLine returns true if line starts with an A-Instruction prefix
 func IsAInstrLine(line string) bool {
 	return strings.HasPrefix(line, string(ainstrLiteral))
@@ -49,6 +61,16 @@ func isVarRune(r rune) bool {
 	return unicode.IsLetter(r) || unicode.IsDigit(r) || ok
 }
 
+// isExprRune returns true if rune starts an operator or a parenthesis of an expression
+func isExprRune(r rune) bool {
+	return strings.ContainsRune("+-*/&|<>()", r)
+}
+
+// isExprStart returns true if an expression (but not a single value) can start with rune
+func isExprStart(r rune) bool {
+	return r == '(' || r == '-'
+}
+
 func checkInlineComment(r *pRuneReader) error {
 	rv, _, err := r.ReadAfterSpaces()
 	if err != nil {
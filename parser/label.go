@@ -12,6 +12,12 @@ type Label struct {
 	Value string
 }
 
+// IsLocal returns true if the label is local, i.e. "(.loop)". A local label belongs
+// to the scope of the previous global label
+func (l Label) IsLocal() bool {
+	return IsLocalName(l.Value)
+}
+
 // LabelParser for Label
 type LabelParser struct {
 	label    Label
@@ -61,6 +67,11 @@ func (p *LabelParser) checkFirst() error {
 		return &ParseError{Pos: p.reader.Pos, Msg: fmt.Sprintf("Label should finish with '%c'", endLabelLiteral)}
 	}
 
+	// A local label starts with a dot followed by a regular name
+	if rv == localLabelLiteral && p.strB.Len() == 0 {
+		p.strB.WriteRune(rv)
+		return nil
+	}
 	if !unicode.IsLetter(rv) && rv != '_' {
 		return &ParseError{
 			Pos: p.reader.Pos,
@@ -72,6 +72,14 @@ func TestParseLabelRegular(t *testing.T) {
 			operator: "(lab1)//Comment",
 			want:     Label{"lab1"},
 		},
+		{
+			operator: "(.loop)",
+			want:     Label{".loop"},
+		},
+		{
+			operator: "(._end.1)",
+			want:     Label{"._end.1"},
+		},
 	}
 
 	p := NewLabelParser()
@@ -106,6 +114,15 @@ func TestParseLabelSyntaxError(t *testing.T) {
 		{
 			operator: "(Label) /a",
 		},
+		{
+			operator: "(.)",
+		},
+		{
+			operator: "(..loop)",
+		},
+		{
+			operator: "(.1loop)",
+		},
 	}
 
 	p := NewLabelParser()
@@ -113,3 +130,22 @@ func TestParseLabelSyntaxError(t *testing.T) {
 		tC.runParseError(p, t)
 	}
 }
+
+func TestLabelIsLocal(t *testing.T) {
+	testCases := []struct {
+		label Label
+		want  bool
+	}{
+		{label: Label{".loop"}, want: true},
+		{label: Label{"LOOP"}, want: false},
+		{label: Label{"MAIN.loop"}, want: false},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.label.Value, func(t *testing.T) {
+			if actual := tC.label.IsLocal(); actual != tC.want {
+				t.Errorf("Actual: %v; want %v", actual, tC.want)
+			}
+		})
+	}
+}
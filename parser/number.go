@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Prefixes of number literals. Decimal numbers have no prefix
+const (
+	hexPrefix   = "0x"
+	binPrefix   = "0b"
+	charLiteral = '\''
+	escapeRune  = '\\'
+
+	// Printable characters of the Hack character set are the same as in ASCII
+	minCharCode = 32
+	maxCharCode = 126
+)
+
+// numRuneFunc returns a function checking digits of a number literal that starts with prefix.
+// If prefix is not a known one, it returns nil
+func numRuneFunc(prefix string) func(rune) bool {
+	switch strings.ToLower(prefix) {
+	case hexPrefix:
+		return func(r rune) bool { return unicode.Is(unicode.ASCII_Hex_Digit, r) }
+	case binPrefix:
+		return func(r rune) bool { return r == '0' || r == '1' }
+	}
+	return nil
+}
+
+// isNumberStart returns true if a number literal can start with the rune
+func isNumberStart(r rune) bool {
+	return unicode.IsDigit(r) || r == charLiteral
+}
+
+// checkChar returns an error if the rune cannot be in a character literal
+func checkChar(r rune) error {
+	if r < minCharCode || r > maxCharCode {
+		return fmt.Errorf("Character %q is not in the Hack character set", r)
+	}
+	return nil
+}
+
+// ParseNumber returns the value of a number literal: a decimal number, a hex number
+// with the prefix 0x, a binary number with the prefix 0b or a character in single
+// quotes like 'A' (the quote and the backslash are escaped by the backslash)
+func ParseNumber(s string) (int, error) {
+	if len(s) > 0 && s[0] == charLiteral {
+		return parseChar(s)
+	}
+
+	base := 10
+	digits := s
+	if len(s) > len(hexPrefix) {
+		switch strings.ToLower(s[:len(hexPrefix)]) {
+		case hexPrefix:
+			base, digits = 16, s[len(hexPrefix):]
+		case binPrefix:
+			base, digits = 2, s[len(binPrefix):]
+		}
+	}
+
+	n, err := strconv.ParseUint(digits, base, 31)
+	if err != nil {
+		if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+			return 0, fmt.Errorf("Number %s is too big", s)
+		}
+		return 0, fmt.Errorf("Wrong number literal '%s'", s)
+	}
+	return int(n), nil
+}
+
+func parseChar(s string) (int, error) {
+	rs := []rune(s)
+	var r rune
+	switch {
+	case len(rs) == 3 && rs[2] == charLiteral && rs[1] != charLiteral && rs[1] != escapeRune:
+		r = rs[1]
+	case len(rs) == 4 && rs[3] == charLiteral && rs[1] == escapeRune && (rs[2] == charLiteral || rs[2] == escapeRune):
+		r = rs[2]
+	default:
+		return 0, fmt.Errorf("Wrong character literal %s", s)
+	}
+	if err := checkChar(r); err != nil {
+		return 0, err
+	}
+	return int(r), nil
+}
@@ -0,0 +1,62 @@
+package parser
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	testCases := []struct {
+		s    string
+		want int
+	}{
+		{s: "0", want: 0},
+		{s: "0123", want: 123},
+		{s: "32767", want: 32767},
+		{s: "0x4000", want: 0x4000},
+		{s: "0X7fff", want: 0x7FFF},
+		{s: "0b1010", want: 10},
+		{s: "0B1", want: 1},
+		{s: "'A'", want: 65},
+		{s: "' '", want: 32},
+		{s: `'\''`, want: 39},
+		{s: `'\\'`, want: 92},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.s, func(t *testing.T) {
+			actual, err := ParseNumber(tC.s)
+			if err != nil {
+				t.Errorf("Unexpected error: %v", err)
+				return
+			}
+			if actual != tC.want {
+				t.Errorf("Actual: %d, want: %d", actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestParseNumberError(t *testing.T) {
+	testCases := []string{
+		"",
+		"-1",
+		"12a",
+		"0x",
+		"0xG",
+		"0b102",
+		"9999999999",
+		"''",
+		"'AB'",
+		"'A",
+		`'\'`,
+		`'\n'`,
+		"'\t'",
+		"'й'",
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC, func(t *testing.T) {
+			if actual, err := ParseNumber(tC); err == nil {
+				t.Errorf("Error was not arisen as expected. Actual: %d", actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// immediatePrefix marks an operand of a pseudo-instruction that is a value, not an address
+const immediatePrefix = "#"
+
+// pseudoInstr is a pseudo-instruction with the given number of operands. It is expanded
+// into real instructions by expand
+type pseudoInstr struct {
+	operands int
+	expand   func(ops []string) ([]string, error)
+}
+
+// Pseudo-instructions. Operands are registers A, D, M, immediate values "#VALUE"
+// or addresses of A-Instructions like labels, variables and expressions
+var pseudoInstrs = map[string]pseudoInstr{
+	"mov":  {operands: 2, expand: expandMov},
+	"goto": {operands: 1, expand: expandGoto},
+	"inc":  {operands: 1, expand: func(ops []string) ([]string, error) { return expandStep(ops[0], "+") }},
+	"dec":  {operands: 1, expand: func(ops []string) ([]string, error) { return expandStep(ops[0], "-") }},
+	"push": {operands: 1, expand: expandPush},
+	"pop":  {operands: 1, expand: expandPop},
+	"jz":   {operands: 2, expand: jumpIf("JEQ")},
+	"jnz":  {operands: 2, expand: jumpIf("JNE")},
+	"jgt":  {operands: 2, expand: jumpIf("JGT")},
+	"jge":  {operands: 2, expand: jumpIf("JGE")},
+	"jlt":  {operands: 2, expand: jumpIf("JLT")},
+	"jle":  {operands: 2, expand: jumpIf("JLE")},
+}
+
+// ExpandPseudo returns real instructions of a pseudo-instruction, i.e. "mov X, Y" is
+// "@Y", "D=M", "@X", "M=D". Operands are separated by commas or spaces. Expansions can
+// change the D register. It returns false if the line is not a pseudo-instruction
+func ExpandPseudo(line string) ([]string, bool, error) {
+	name, args := SplitArg(StripComment(line))
+	pi, ok := pseudoInstrs[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	ops := splitOperands(args)
+	if len(ops) != pi.operands {
+		return nil, true, &ParseError{
+			Pos: 1,
+			Msg: fmt.Sprintf("Pseudo-instruction '%s' expects %d operands, but %d are given", name, pi.operands, len(ops)),
+		}
+	}
+	lines, err := pi.expand(ops)
+	if err != nil {
+		return nil, true, &ParseError{Pos: 1, Msg: fmt.Sprintf("Pseudo-instruction '%s': %v", name, err)}
+	}
+	return lines, true, nil
+}
+
+// splitOperands splits by commas if there is any, otherwise by spaces
+func splitOperands(args string) []string {
+	if args == "" {
+		return nil
+	}
+	if !strings.ContainsRune(args, ',') {
+		return strings.FieldsFunc(args, unicode.IsSpace)
+	}
+	ops := strings.Split(args, ",")
+	for i := range ops {
+		ops[i] = strings.TrimSpace(ops[i])
+	}
+	return ops
+}
+
+func isRegister(op string) bool {
+	return op == "A" || op == "D" || op == "M"
+}
+
+// aInstr returns A-Instruction with the operand. The operand is checked by AParser.
+// Local labels are replaced with full names later, so they are checked as global labels
+func aInstr(op string) (string, error) {
+	s := string(ainstrLiteral) + strings.TrimPrefix(op, immediatePrefix)
+	checked := ReplaceSymbols(s, func(name string) string {
+		if IsLocalName(name) {
+			return "L" + name
+		}
+		return name
+	})
+	if _, err := NewAParser().Parse(checked); err != nil {
+		return "", fmt.Errorf("wrong operand '%s'", op)
+	}
+	return s, nil
+}
+
+// loadD returns instructions that put the value of the operand into D
+func loadD(op string) ([]string, error) {
+	switch {
+	case op == "D":
+		return nil, nil
+	case isRegister(op):
+		return []string{"D=" + op}, nil
+	}
+	a, err := aInstr(op)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasPrefix(op, immediatePrefix) {
+		return []string{a, "D=A"}, nil
+	}
+	return []string{a, "D=M"}, nil
+}
+
+// expandMov expands "mov DST, SRC"
+func expandMov(ops []string) ([]string, error) {
+	dst, src := ops[0], ops[1]
+	switch {
+	case strings.HasPrefix(dst, immediatePrefix):
+		return nil, fmt.Errorf("destination cannot be an immediate value")
+	case isRegister(dst) && isRegister(src):
+		return []string{dst + "=" + src}, nil
+	case dst == "M":
+		return nil, fmt.Errorf("M can be set only from a register")
+	case isRegister(dst):
+		a, err := aInstr(src)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case strings.HasPrefix(src, immediatePrefix) && dst == "A":
+			return []string{a}, nil
+		case strings.HasPrefix(src, immediatePrefix):
+			return []string{a, dst + "=A"}, nil
+		}
+		return []string{a, dst + "=M"}, nil
+	}
+
+	lines, err := loadD(src)
+	if err != nil {
+		return nil, err
+	}
+	a, err := aInstr(dst)
+	if err != nil {
+		return nil, err
+	}
+	return append(lines, a, "M=D"), nil
+}
+
+// expandGoto expands "goto LABEL"
+func expandGoto(ops []string) ([]string, error) {
+	a, err := aInstr(ops[0])
+	if err != nil {
+		return nil, err
+	}
+	return []string{a, "0;JMP"}, nil
+}
+
+// expandStep expands "inc OP" and "dec OP" where op is "+" or "-"
+func expandStep(target, op string) ([]string, error) {
+	if isRegister(target) {
+		return []string{target + "=" + target + op + "1"}, nil
+	}
+	a, err := aInstr(target)
+	if err != nil || strings.HasPrefix(target, immediatePrefix) {
+		return nil, fmt.Errorf("wrong operand '%s'", target)
+	}
+	return []string{a, "M=M" + op + "1"}, nil
+}
+
+// expandPush expands "push D": the stack pointer is SP
+func expandPush(ops []string) ([]string, error) {
+	if ops[0] != "D" {
+		return nil, fmt.Errorf("only D can be pushed")
+	}
+	return []string{"@SP", "A=M", "M=D", "@SP", "M=M+1"}, nil
+}
+
+// expandPop expands "pop D": the stack pointer is SP
+func expandPop(ops []string) ([]string, error) {
+	if ops[0] != "D" {
+		return nil, fmt.Errorf("only D can be popped")
+	}
+	return []string{"@SP", "AM=M-1", "D=M"}, nil
+}
+
+// jumpIf returns the expansion of "jz D, LABEL" with the jump
+func jumpIf(jump string) func(ops []string) ([]string, error) {
+	return func(ops []string) ([]string, error) {
+		if ops[0] != "D" {
+			return nil, fmt.Errorf("only D can be compared")
+		}
+		a, err := aInstr(ops[1])
+		if err != nil {
+			return nil, err
+		}
+		return []string{a, "D;" + jump}, nil
+	}
+}
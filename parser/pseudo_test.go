@@ -0,0 +1,81 @@
+package parser
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestExpandPseudo(t *testing.T) {
+	testCases := []struct {
+		line string
+		want []string
+	}{
+		{line: "mov X, Y", want: []string{"@Y", "D=M", "@X", "M=D"}},
+		{line: "mov X, #SCREEN+1", want: []string{"@SCREEN+1", "D=A", "@X", "M=D"}},
+		{line: "mov X D", want: []string{"@X", "M=D"}},
+		{line: "mov D, X", want: []string{"@X", "D=M"}},
+		{line: "mov D, #5", want: []string{"@5", "D=A"}},
+		{line: "mov A, #5", want: []string{"@5"}},
+		{line: "mov M, D", want: []string{"M=D"}},
+		{line: "goto LOOP // forever", want: []string{"@LOOP", "0;JMP"}},
+		{line: "jz D LOOP", want: []string{"@LOOP", "D;JEQ"}},
+		{line: "jle D, END-1", want: []string{"@END-1", "D;JLE"}},
+		{line: "goto .loop", want: []string{"@.loop", "0;JMP"}},
+		{line: "jnz D .end+1", want: []string{"@.end+1", "D;JNE"}},
+		{line: "mov .x, #5", want: []string{"@5", "D=A", "@.x", "M=D"}},
+		{line: "inc M", want: []string{"M=M+1"}},
+		{line: "dec i", want: []string{"@i", "M=M-1"}},
+		{line: "push D", want: []string{"@SP", "A=M", "M=D", "@SP", "M=M+1"}},
+		{line: "pop D", want: []string{"@SP", "AM=M-1", "D=M"}},
+	}
+
+	for _, tC := range testCases {
+		t.Run(tC.line, func(t *testing.T) {
+			actual, ok, err := ExpandPseudo(tC.line)
+			if err != nil || !ok {
+				t.Errorf("Unexpected result: %v, %v", ok, err)
+				return
+			}
+			if !reflect.DeepEqual(actual, tC.want) {
+				t.Errorf("Actual: %q; want %q", actual, tC.want)
+			}
+		})
+	}
+}
+
+func TestExpandPseudoNotPseudo(t *testing.T) {
+	for _, line := range []string{"D=M", "@mov", "(goto)", "MOV X, Y"} {
+		t.Run(line, func(t *testing.T) {
+			if _, ok, err := ExpandPseudo(line); ok || err != nil {
+				t.Errorf("Unexpected result: %v, %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestExpandPseudoError(t *testing.T) {
+	testCases := []string{
+		"mov X",
+		"mov #5, X",
+		"mov M, X",
+		"mov X, 1Y",
+		"goto .1loop",
+		"goto",
+		"inc #5",
+		"push A",
+		"pop M",
+		"jz M, END",
+		"jnz D",
+	}
+
+	for _, line := range testCases {
+		t.Run(line, func(t *testing.T) {
+			_, ok, err := ExpandPseudo(line)
+			var pe *ParseError
+			if !ok || !errors.As(err, &pe) {
+				t.Errorf("Unexpected result: %v, %v", ok, err)
+			}
+		})
+	}
+}
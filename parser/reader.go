@@ -35,3 +35,8 @@ func (rR *pRuneReader) ReadAfterSpaces() (rune, int, error) {
 		}
 	}
 }
+
+// Offset returns the byte offset of the next rune in the string
+func (rR *pRuneReader) Offset() int {
+	return int(rR.reader.Size()) - rR.reader.Len()
+}
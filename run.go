@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/assembler"
+	"github.com/verybigtuple/hackassembler/code"
+	"github.com/verybigtuple/hackassembler/cpu"
+	"github.com/verybigtuple/hackassembler/parser"
+)
+
+// ramValues is a flag that can be set several times as "addr=value".
+// Address can be a number or a predefined symbol like R0 or SCREEN. Value is a number
+type ramValues map[int]int
+
+func (v ramValues) String() string {
+	return fmt.Sprint(map[int]int(v))
+}
+
+func (v ramValues) Set(s string) error {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 {
+		return fmt.Errorf("'%s' must be set as addr=value", s)
+	}
+	addr, err := parser.ParseNumber(parts[0])
+	if err != nil {
+		if addr, err = code.NewSymbolTable().Get(parts[0]); err != nil {
+			return err
+		}
+	}
+	if addr < 0 || addr >= cpu.RAMSize {
+		return fmt.Errorf("RAM address %d is out of bound", addr)
+	}
+	val, err := parseValue(parts[1])
+	if err != nil {
+		return err
+	}
+	v[addr] = val
+	return nil
+}
+
+// parseValue parses a 16-bit value: a number literal that can have a leading minus.
+// Negative values are two's complement, so the value is in -32768..65535
+func parseValue(s string) (int, error) {
+	val, err := parser.ParseNumber(strings.TrimPrefix(s, "-"))
+	if strings.HasPrefix(s, "-") {
+		val = -val
+	}
+	if err != nil || val < math.MinInt16 || val > math.MaxUint16 {
+		return 0, fmt.Errorf("'%s' is not a 16-bit number", s)
+	}
+	return val, nil
+}
+
+// parseRange parses "from:to" where to is not included
+func parseRange(s string) (from, to int, err error) {
+	parts := strings.SplitN(s, ":", 2)
+	if len(parts) == 2 {
+		from, err = parser.ParseNumber(parts[0])
+		if err == nil {
+			to, err = parser.ParseNumber(parts[1])
+		}
+	}
+	if len(parts) != 2 || err != nil || from < 0 || to > cpu.RAMSize || from > to {
+		return 0, 0, fmt.Errorf("'%s' is not a valid RAM range from:to", s)
+	}
+	return from, to, nil
+}
+
+// loadProgram returns CPU with a loaded program. Files *.asm are assembled before loading,
+// all other files are loaded as binary code
+func loadProgram(name string) (*cpu.CPU, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open input file: %v", err)
+	}
+	defer f.Close()
+
+	c := cpu.New()
+	if filepath.Ext(name) != ".asm" {
+		return c, c.LoadHack(f)
+	}
+
+	prog, err := assembler.Assemble(f, assembler.Options{Name: name})
+	if err != nil {
+		return nil, err
+	}
+	return c, c.Load(prog.Words)
+}
+
+// loadRAM loads a RAM image file
+func loadRAM(c *cpu.CPU, name string) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return fmt.Errorf("Cannot open RAM image: %v", err)
+	}
+	defer f.Close()
+
+	if err := c.LoadRAMHack(f); err != nil {
+		return fmt.Errorf("Cannot load RAM image %s: %v", name, err)
+	}
+	return nil
+}
+
+// runMain runs "run" command with its arguments and returns the exit code
+func runMain(args []string) int {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	inFileFlag := fs.String("in", "", "Program to run: a file *.hack or *.asm")
+	cyclesFlag := fs.Int("cycles", 10000, "Number of cycles to run")
+	ramFlag := fs.String("ram", "0:16", "Range of RAM addresses from:to to print")
+	dataFlag := fs.String("data", "", "Optional RAM image (*.hack format) loaded before running, i.e. data of the assembler")
+	setFlag := ramValues{}
+	fs.Var(setFlag, "set", "Set RAM before running as addr=value, e.g. R0=5. Can be repeated")
+	fs.Parse(args)
+
+	if *inFileFlag == "" {
+		fmt.Fprintln(os.Stderr, "Input file is not set")
+		return fileError
+	}
+	from, to, err := parseRange(*ramFlag)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return otherError
+	}
+
+	c, err := loadProgram(*inFileFlag)
+	if err != nil {
+		return printErrors(err)
+	}
+	if *dataFlag != "" {
+		if err := loadRAM(c, *dataFlag); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return fileError
+		}
+	}
+	for addr, val := range setFlag {
+		c.RAM[addr] = uint16(val)
+	}
+
+	c.Run(*cyclesFlag)
+
+	fmt.Printf("Time: %d\n", c.Time)
+	fmt.Printf("PC: %d\n", c.PC)
+	fmt.Printf("A: %d\n", int16(c.A))
+	fmt.Printf("D: %d\n", int16(c.D))
+	for addr := from; addr < to; addr++ {
+		fmt.Printf("RAM[%d]: %d\n", addr, int16(c.RAM[addr]))
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/verybigtuple/hackassembler/tst"
+)
+
+// testMain runs "test" command: executes test scripts *.tst and returns the exit code
+func testMain(args []string) int {
+	fs := flag.NewFlagSet("test", flag.ExitOnError)
+	echoFlag := fs.Bool("echo", false, "Print messages of echo commands")
+	fs.Parse(args)
+
+	if fs.NArg() == 0 {
+		fmt.Fprintln(os.Stderr, "Test scripts are not set")
+		return fileError
+	}
+
+	opts := tst.Options{}
+	if *echoFlag {
+		opts.Echo = os.Stdout
+	}
+
+	exitCode := 0
+	for _, script := range fs.Args() {
+		if err := tst.Run(script, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "FAIL %s\n%v\n", script, err)
+			exitCode = testError
+			continue
+		}
+		fmt.Printf("ok   %s\n", script)
+	}
+	return exitCode
+}
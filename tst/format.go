@@ -0,0 +1,86 @@
+package tst
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const defaultFormat = "%D1.6.1"
+
+// outputVar is an item of output-list, e.g. RAM[0]%D2.6.2: variable name, format (B, D, X or S),
+// the number of spaces on the left, the length of the value and the number of spaces on the right
+type outputVar struct {
+	name   string
+	format byte
+	left   int
+	length int
+	right  int
+}
+
+func parseOutputVar(s string) (outputVar, error) {
+	name, spec := s, defaultFormat
+	if i := strings.IndexRune(s, '%'); i >= 0 {
+		name, spec = s[:i], s[i:]
+	}
+
+	v := outputVar{name: name}
+	parts := strings.Split(spec[min(2, len(spec)):], ".")
+	if name == "" || len(spec) < 2 || !strings.ContainsRune("BDXS", rune(spec[1])) || len(parts) != 3 {
+		return outputVar{}, fmt.Errorf("Wrong output format '%s'", s)
+	}
+	v.format = spec[1]
+
+	nums := []*int{&v.left, &v.length, &v.right}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return outputVar{}, fmt.Errorf("Wrong output format '%s'", s)
+		}
+		*nums[i] = n
+	}
+	return v, nil
+}
+
+func (v outputVar) width() int {
+	return v.left + v.length + v.right
+}
+
+// header returns the name of the variable centered in the column
+func (v outputVar) header() string {
+	name := v.name
+	if len(name) > v.width() {
+		name = name[:v.width()]
+	}
+	left := (v.width() - len(name)) / 2
+	right := v.width() - len(name) - left
+	return strings.Repeat(" ", left) + name + strings.Repeat(" ", right)
+}
+
+// cell returns the formatted value of the variable
+func (v outputVar) cell(val uint16) string {
+	var s string
+	switch v.format {
+	case 'B':
+		s = lastChars(fmt.Sprintf("%016b", val), v.length)
+	case 'X':
+		s = lastChars(fmt.Sprintf("%04X", val), v.length)
+	default:
+		s = strconv.Itoa(int(int16(val)))
+	}
+	return strings.Repeat(" ", v.left) + fmt.Sprintf("%*s", v.length, s) + strings.Repeat(" ", v.right)
+}
+
+func lastChars(s string, n int) string {
+	if len(s) > n {
+		return s[len(s)-n:]
+	}
+	return s
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
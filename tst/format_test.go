@@ -0,0 +1,50 @@
+package tst
+
+import (
+	"testing"
+)
+
+func TestOutputVar(t *testing.T) {
+	testCases := []struct {
+		spec   string
+		val    uint16
+		header string
+		cell   string
+	}{
+		{spec: "RAM[0]%D2.6.2", val: 5, header: "  RAM[0]  ", cell: "       5  "},
+		{spec: "RAM[0]%D2.6.2", val: 0xFFFF, header: "  RAM[0]  ", cell: "      -1  "},
+		{spec: "A%X1.4.1", val: 0x3F2, header: "  A   ", cell: " 03F2 "},
+		{spec: "D%B0.16.0", val: 5, header: "       D        ", cell: "0000000000000101"},
+		{spec: "PC%B0.4.0", val: 5, header: " PC ", cell: "0101"},
+		{spec: "time%S0.2.0", val: 12, header: "ti", cell: "12"},
+		{spec: "PC", val: 17, header: "   PC   ", cell: "     17 "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			v, err := parseOutputVar(tc.spec)
+			if err != nil {
+				t.Errorf("Error from function: %v", err)
+				return
+			}
+			if h := v.header(); h != tc.header {
+				t.Errorf("Header: %q; want %q", h, tc.header)
+			}
+			if c := v.cell(tc.val); c != tc.cell {
+				t.Errorf("Cell: %q; want %q", c, tc.cell)
+			}
+		})
+	}
+}
+
+func TestOutputVarError(t *testing.T) {
+	testCases := []string{"%D1.2.3", "A%Q1.2.3", "A%D1.2", "A%D1.x.3", "A%"}
+
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			if _, err := parseOutputVar(tc); err == nil {
+				t.Errorf("Error was not arisen as expected")
+			}
+		})
+	}
+}
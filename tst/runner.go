@@ -0,0 +1,423 @@
+package tst
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/verybigtuple/hackassembler/assembler"
+	"github.com/verybigtuple/hackassembler/cpu"
+)
+
+// ComparisonError is returned if an output line differs from the line of the compare file
+type ComparisonError struct {
+	File   string
+	Line   int
+	Output string
+	Want   string
+}
+
+func (e *ComparisonError) Error() string {
+	return fmt.Sprintf("%s:%d: Comparison failure: %q; want %q", e.File, e.Line, e.Output, e.Want)
+}
+
+// Options of Run
+type Options struct {
+	// Echo is a writer for echo commands. If it is nil, echo commands are ignored
+	Echo io.Writer
+}
+
+var ramRegexp = regexp.MustCompile(`^RAM\[(\d+)\]$`)
+
+type runner struct {
+	file string
+	dir  string
+	opts Options
+	cpu  *cpu.CPU
+
+	out      *bufio.Writer
+	outFile  *os.File
+	vars     []outputVar
+	cmpLines []string
+	cmpFile  string
+	outLines int
+}
+
+// Run executes the test script from a file. Paths in the script are relative to the script dir.
+// It returns ComparisonError on the first line that differs from the compare file
+func Run(file string, opts Options) error {
+	src, err := ioutil.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	cmds, err := parseScript(file, string(src))
+	if err != nil {
+		return err
+	}
+
+	r := &runner{file: file, dir: filepath.Dir(file), opts: opts, cpu: cpu.New()}
+	defer r.closeOutput()
+
+	if err := r.execBlock(cmds); err != nil {
+		return err
+	}
+	return r.closeOutput()
+}
+
+func (r *runner) errorf(cmd command, format string, a ...interface{}) error {
+	return &ScriptError{File: r.file, Line: cmd.line, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (r *runner) execBlock(cmds []command) error {
+	for _, cmd := range cmds {
+		if err := r.exec(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *runner) exec(cmd command) error {
+	if cmd.body != nil && cmd.name != "repeat" && cmd.name != "while" {
+		return r.errorf(cmd, "Command '%s' cannot have a block", cmd.name)
+	}
+
+	switch cmd.name {
+	case "load":
+		return r.load(cmd)
+	case "output-file":
+		return r.outputFile(cmd)
+	case "compare-to":
+		return r.compareTo(cmd)
+	case "output-list":
+		return r.outputList(cmd)
+	case "output":
+		return r.output(cmd)
+	case "set":
+		return r.set(cmd)
+	case "ticktock":
+		r.cpu.Step()
+		return nil
+	case "repeat":
+		return r.repeat(cmd)
+	case "while":
+		return r.while(cmd)
+	case "echo":
+		if r.opts.Echo != nil {
+			fmt.Fprintln(r.opts.Echo, strings.Join(cmd.args, " "))
+		}
+		return nil
+	case "clear-echo":
+		return nil
+	}
+	return r.errorf(cmd, "Unknown command '%s'", cmd.name)
+}
+
+func (r *runner) path(name string) string {
+	if filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(r.dir, name)
+}
+
+func (r *runner) checkArgs(cmd command, n int) error {
+	if len(cmd.args) != n {
+		return r.errorf(cmd, "Command '%s' must have %d argument(s)", cmd.name, n)
+	}
+	return nil
+}
+
+// load loads a program into ROM. Files *.asm are assembled before loading
+func (r *runner) load(cmd command) error {
+	if err := r.checkArgs(cmd, 1); err != nil {
+		return err
+	}
+	name := r.path(cmd.args[0])
+	f, err := os.Open(name)
+	if err != nil {
+		return r.errorf(cmd, "Cannot load program: %v", err)
+	}
+	defer f.Close()
+
+	if filepath.Ext(name) != ".asm" {
+		err = r.cpu.LoadHack(f)
+	} else {
+		var prog *assembler.Program
+		prog, err = assembler.Assemble(f, assembler.Options{Name: name})
+		if err == nil {
+			err = r.cpu.Load(prog.Words)
+		}
+	}
+	if err != nil {
+		return r.errorf(cmd, "Cannot load program: %v", err)
+	}
+	r.cpu.Reset()
+	return nil
+}
+
+func (r *runner) outputFile(cmd command) error {
+	if err := r.checkArgs(cmd, 1); err != nil {
+		return err
+	}
+	if err := r.closeOutput(); err != nil {
+		return err
+	}
+	f, err := os.Create(r.path(cmd.args[0]))
+	if err != nil {
+		return r.errorf(cmd, "Cannot create output file: %v", err)
+	}
+	r.outFile = f
+	r.out = bufio.NewWriter(f)
+	r.outLines = 0
+	return nil
+}
+
+func (r *runner) closeOutput() error {
+	if r.outFile == nil {
+		return nil
+	}
+	err := r.out.Flush()
+	if cerr := r.outFile.Close(); err == nil {
+		err = cerr
+	}
+	r.outFile, r.out = nil, nil
+	return err
+}
+
+func (r *runner) compareTo(cmd command) error {
+	if err := r.checkArgs(cmd, 1); err != nil {
+		return err
+	}
+	name := r.path(cmd.args[0])
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return r.errorf(cmd, "Cannot read compare file: %v", err)
+	}
+	r.cmpFile = name
+	r.cmpLines = strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	return nil
+}
+
+// outputList sets the list of output variables and writes the header line
+func (r *runner) outputList(cmd command) error {
+	vars := make([]outputVar, 0, len(cmd.args))
+	for _, a := range cmd.args {
+		v, err := parseOutputVar(a)
+		if err != nil {
+			return r.errorf(cmd, "%v", err)
+		}
+		vars = append(vars, v)
+	}
+	r.vars = vars
+
+	sb := strings.Builder{}
+	sb.WriteString("|")
+	for _, v := range r.vars {
+		sb.WriteString(v.header() + "|")
+	}
+	return r.writeLine(sb.String())
+}
+
+func (r *runner) output(cmd command) error {
+	if err := r.checkArgs(cmd, 0); err != nil {
+		return err
+	}
+	sb := strings.Builder{}
+	sb.WriteString("|")
+	for _, v := range r.vars {
+		val, err := r.get(v.name)
+		if err != nil {
+			return r.errorf(cmd, "%v", err)
+		}
+		sb.WriteString(v.cell(val) + "|")
+	}
+	return r.writeLine(sb.String())
+}
+
+// writeLine writes the line into the output file and compares it with the compare file
+func (r *runner) writeLine(line string) error {
+	if r.out != nil {
+		r.out.WriteString(line + "\n")
+	}
+	r.outLines++
+
+	// Lines after the end of the compare file are not compared
+	if r.outLines > len(r.cmpLines) {
+		return nil
+	}
+	want := strings.TrimRight(r.cmpLines[r.outLines-1], " \t")
+	if !matchLine(line, want) {
+		return &ComparisonError{File: r.cmpFile, Line: r.outLines, Output: line, Want: want}
+	}
+	return nil
+}
+
+// matchLine compares lines. The symbol '*' in the compare line matches any symbol
+func matchLine(line, want string) bool {
+	line = strings.TrimRight(line, " \t")
+	if len(line) != len(want) {
+		return false
+	}
+	for i := 0; i < len(line); i++ {
+		if want[i] != '*' && want[i] != line[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *runner) set(cmd command) error {
+	if err := r.checkArgs(cmd, 2); err != nil {
+		return err
+	}
+	val, err := parseValue(cmd.args[1])
+	if err != nil {
+		return r.errorf(cmd, "%v", err)
+	}
+
+	c := r.cpu
+	switch name := cmd.args[0]; name {
+	case "A":
+		c.A = val
+	case "D":
+		c.D = val
+	case "PC":
+		c.PC = val
+	default:
+		addr, err := ramAddr(name)
+		if err != nil {
+			return r.errorf(cmd, "%v", err)
+		}
+		c.RAM[addr] = val
+	}
+	return nil
+}
+
+// get returns the value of a variable: A, D, PC, time or RAM[n]
+func (r *runner) get(name string) (uint16, error) {
+	c := r.cpu
+	switch name {
+	case "A":
+		return c.A, nil
+	case "D":
+		return c.D, nil
+	case "PC":
+		return c.PC, nil
+	case "time":
+		return uint16(c.Time), nil
+	}
+	addr, err := ramAddr(name)
+	if err != nil {
+		return 0, err
+	}
+	return c.RAM[addr], nil
+}
+
+func ramAddr(name string) (int, error) {
+	m := ramRegexp.FindStringSubmatch(name)
+	if m == nil {
+		return 0, fmt.Errorf("Unknown variable '%s'", name)
+	}
+	addr, err := strconv.Atoi(m[1])
+	if err != nil || addr >= cpu.RAMSize {
+		return 0, fmt.Errorf("RAM address in '%s' is out of bound", name)
+	}
+	return addr, nil
+}
+
+// parseValue parses a decimal number or a number with a format prefix: %D, %X or %B
+func parseValue(s string) (uint16, error) {
+	base := 10
+	num := s
+	if len(s) > 2 && s[0] == '%' {
+		switch s[1] {
+		case 'D':
+		case 'X':
+			base = 16
+		case 'B':
+			base = 2
+		default:
+			return 0, fmt.Errorf("Wrong value format '%s'", s)
+		}
+		num = s[2:]
+	}
+
+	if base == 10 {
+		n, err := strconv.ParseInt(num, 10, 16)
+		if err != nil {
+			return 0, fmt.Errorf("'%s' is not a 16-bit number", s)
+		}
+		return uint16(n), nil
+	}
+	n, err := strconv.ParseUint(num, base, 16)
+	if err != nil {
+		return 0, fmt.Errorf("'%s' is not a 16-bit number", s)
+	}
+	return uint16(n), nil
+}
+
+func (r *runner) repeat(cmd command) error {
+	if err := r.checkArgs(cmd, 1); err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(cmd.args[0])
+	if err != nil || n < 0 {
+		return r.errorf(cmd, "Wrong repeat count '%s'", cmd.args[0])
+	}
+	for i := 0; i < n; i++ {
+		if err := r.execBlock(cmd.body); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// while executes the block while the condition "var op value" is true
+func (r *runner) while(cmd command) error {
+	if err := r.checkArgs(cmd, 3); err != nil {
+		return err
+	}
+	val, err := parseValue(cmd.args[2])
+	if err != nil {
+		return r.errorf(cmd, "%v", err)
+	}
+	op := cmd.args[1]
+	if !strings.Contains(" = <> < > <= >= ", " "+op+" ") {
+		return r.errorf(cmd, "Unknown operator '%s'", op)
+	}
+
+	for {
+		v, err := r.get(cmd.args[0])
+		if err != nil {
+			return r.errorf(cmd, "%v", err)
+		}
+		if !compare(int16(v), op, int16(val)) {
+			return nil
+		}
+		if err := r.execBlock(cmd.body); err != nil {
+			return err
+		}
+	}
+}
+
+func compare(a int16, op string, b int16) bool {
+	switch op {
+	case "=":
+		return a == b
+	case "<>":
+		return a != b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	}
+	return a >= b
+}
@@ -0,0 +1,80 @@
+package tst
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyTestdata copies testdata into a temp dir as scripts write output files near themselves
+func copyTestdata(t *testing.T) string {
+	dir := t.TempDir()
+	files, err := ioutil.ReadDir("testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, f.Name()), data, os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestRun(t *testing.T) {
+	dir := copyTestdata(t)
+	if err := Run(filepath.Join(dir, "Max.tst"), Options{}); err != nil {
+		t.Errorf("Error from function: %v", err)
+		return
+	}
+
+	out, err := ioutil.ReadFile(filepath.Join(dir, "Max.out"))
+	if err != nil {
+		t.Errorf("Output file is not written: %v", err)
+		return
+	}
+	want, _ := ioutil.ReadFile(filepath.Join(dir, "Max.cmp"))
+	if string(out) != string(want) {
+		t.Errorf("Output:\n%s\nwant:\n%s", out, want)
+	}
+}
+
+func TestRunComparisonFailure(t *testing.T) {
+	dir := copyTestdata(t)
+	err := Run(filepath.Join(dir, "MaxFail.tst"), Options{})
+	var ce *ComparisonError
+	if !errors.As(err, &ce) {
+		t.Errorf("Error is not ComparisonError: %v", err)
+		return
+	}
+	if ce.Line != 4 {
+		t.Errorf("Failed line: %v; want %v", ce.Line, 4)
+	}
+}
+
+func TestParseScriptError(t *testing.T) {
+	testCases := []string{
+		"load Max.asm",
+		"repeat 5 { ticktock;",
+		"ticktock; }",
+		"/* comment",
+		"echo \"text;",
+		"; output;",
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc, func(t *testing.T) {
+			_, err := parseScript("test.tst", tc)
+			var se *ScriptError
+			if !errors.As(err, &se) {
+				t.Errorf("Error is not ScriptError: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,179 @@
+// Package tst runs nand2tetris CPUEmulator test scripts (*.tst) and compares
+// their output with compare files (*.cmp)
+package tst
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ScriptError is an error in a test script or its execution
+type ScriptError struct {
+	File string
+	Line int
+	Msg  string
+}
+
+func (e *ScriptError) Error() string {
+	return fmt.Sprintf("%s:%d: %s", e.File, e.Line, e.Msg)
+}
+
+type tokenKind int
+
+const (
+	wordToken tokenKind = iota
+	stringToken
+	sepToken
+	openToken
+	closeToken
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	line int
+}
+
+// Command separators. There is no difference between them for the runner
+const seps = ",;!"
+
+// command is a single script command. Body is set for loops
+type command struct {
+	name string
+	args []string
+	line int
+	body []command
+}
+
+// tokenize splits the script into tokens skipping spaces and comments
+func tokenize(file, src string) ([]token, error) {
+	var tokens []token
+	rs := []rune(src)
+	line := 1
+
+	for i := 0; i < len(rs); i++ {
+		r := rs[i]
+		switch {
+		case r == '\n':
+			line++
+		case unicode.IsSpace(r):
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '/':
+			for i < len(rs) && rs[i] != '\n' {
+				i++
+			}
+			line++
+		case r == '/' && i+1 < len(rs) && rs[i+1] == '*':
+			startLine := line
+			for i += 2; i+1 < len(rs) && !(rs[i] == '*' && rs[i+1] == '/'); i++ {
+				if rs[i] == '\n' {
+					line++
+				}
+			}
+			if i+1 >= len(rs) {
+				return nil, &ScriptError{File: file, Line: startLine, Msg: "Comment is not closed"}
+			}
+			i++
+		case r == '"':
+			end := strings.IndexRune(string(rs[i+1:]), '"')
+			if end < 0 {
+				return nil, &ScriptError{File: file, Line: line, Msg: "String is not closed"}
+			}
+			s := string(rs[i+1:])[:end]
+			tokens = append(tokens, token{kind: stringToken, text: s, line: line})
+			i += len([]rune(s)) + 1
+		case strings.ContainsRune(seps, r):
+			tokens = append(tokens, token{kind: sepToken, text: string(r), line: line})
+		case r == '{':
+			tokens = append(tokens, token{kind: openToken, text: string(r), line: line})
+		case r == '}':
+			tokens = append(tokens, token{kind: closeToken, text: string(r), line: line})
+		default:
+			start := i
+			for i+1 < len(rs) && isWordRune(rs[i+1]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: wordToken, text: string(rs[start : i+1]), line: line})
+		}
+	}
+	return tokens, nil
+}
+
+func isWordRune(r rune) bool {
+	return !unicode.IsSpace(r) && !strings.ContainsRune(seps+"{}\"", r)
+}
+
+// parseScript returns commands of the script
+func parseScript(file, src string) ([]command, error) {
+	tokens, err := tokenize(file, src)
+	if err != nil {
+		return nil, err
+	}
+	p := scriptParser{file: file, tokens: tokens}
+	cmds, err := p.parseBlock()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, p.errorf(p.tokens[p.pos].line, "Unexpected '%s'", p.tokens[p.pos].text)
+	}
+	return cmds, nil
+}
+
+type scriptParser struct {
+	file   string
+	tokens []token
+	pos    int
+}
+
+func (p *scriptParser) errorf(line int, format string, a ...interface{}) error {
+	return &ScriptError{File: p.file, Line: line, Msg: fmt.Sprintf(format, a...)}
+}
+
+// parseBlock parses commands up to the end of the script or to the closing brace
+func (p *scriptParser) parseBlock() ([]command, error) {
+	var cmds []command
+	for p.pos < len(p.tokens) && p.tokens[p.pos].kind != closeToken {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
+
+func (p *scriptParser) parseCommand() (command, error) {
+	t := p.tokens[p.pos]
+	if t.kind != wordToken {
+		return command{}, p.errorf(t.line, "Unexpected '%s'", t.text)
+	}
+	cmd := command{name: t.text, line: t.line}
+	p.pos++
+
+	for ; p.pos < len(p.tokens); p.pos++ {
+		t = p.tokens[p.pos]
+		switch t.kind {
+		case wordToken, stringToken:
+			cmd.args = append(cmd.args, t.text)
+		case sepToken:
+			p.pos++
+			return cmd, nil
+		case openToken:
+			p.pos++
+			body, err := p.parseBlock()
+			if err != nil {
+				return command{}, err
+			}
+			if p.pos >= len(p.tokens) {
+				return command{}, p.errorf(cmd.line, "Block of '%s' is not closed", cmd.name)
+			}
+			p.pos++
+			cmd.body = body
+			return cmd, nil
+		default:
+			return command{}, p.errorf(t.line, "Unexpected '%s'", t.text)
+		}
+	}
+	return command{}, p.errorf(cmd.line, "Command '%s' must end with one of '%s'", cmd.name, seps)
+}
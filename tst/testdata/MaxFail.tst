@@ -0,0 +1,39 @@
+// Tests Max.asm on the CPU emulator
+load Max.asm,
+output-file Max.out,
+compare-to MaxFail.cmp,
+output-list RAM[0]%D2.6.2 RAM[1]%D2.6.2 RAM[2]%D2.6.2;
+
+set PC 0,
+set RAM[0] 0,   // Set test arguments
+set RAM[1] 0;
+repeat 14 {
+  ticktock;
+}
+output;
+
+set PC 0,
+set RAM[0] 1,
+set RAM[1] 0;
+repeat 10 {
+  ticktock;
+}
+output;
+
+/* The second number
+   is greater */
+set PC 0,
+set RAM[0] 0,
+set RAM[1] 2;
+repeat 14 {
+  ticktock;
+}
+output;
+
+set PC 0,
+set RAM[0] %D1000,
+set RAM[1] %X3F2;
+repeat 14 {
+  ticktock;
+}
+output;